@@ -19,6 +19,7 @@ type (
 		server     *Server
 		support    Supporter
 		viewEngine *ViewEngine
+		worker     *Worker
 	}
 )
 
@@ -33,17 +34,21 @@ func NewApp(asset *Asset, viewFuncs map[string]interface{}) *App {
 	support := &Support{}
 	logger := NewLogger()
 	config := NewConfig(asset, logger, support)
-	dbManager := NewDBManager(logger, support)
+	logger.SetDBSlowQueryThreshold(config.DBSlowQueryThreshold)
+	dbManager := NewDBManager(asset, logger, support)
 	i18n := NewI18n(asset, config, logger)
 	viewEngine := NewViewEngine(asset, config, logger)
 	server := NewServer(asset, config, logger, support)
-	mailer := NewMailer(asset, config, i18n, logger, server, viewFuncs)
+	server.setupSessionStore(dbManager)
+	worker := NewWorker(config, logger)
+	mailer := NewMailer(asset, config, i18n, logger, server, worker, viewFuncs)
 
 	// Setup the default middleware.
 	server.Use(AttachLogger(logger))
 	server.Use(AttachI18n(i18n))
 	server.Use(AttachMailer(mailer))
 	server.Use(AttachViewEngine(asset, config, logger, viewFuncs))
+	server.Use(AttachWorker(worker))
 	server.Use(RealIP())
 	server.Use(RequestID())
 	server.Use(RequestLogger(config, logger))
@@ -53,7 +58,12 @@ func NewApp(asset *Asset, viewFuncs map[string]interface{}) *App {
 	server.Use(CSRF(config, logger, support))
 	server.Use(Secure(config))
 	server.Use(APIOnlyResponse())
-	server.Use(SessionManager(config))
+
+	// The session store failure is reported by server.Errors() which stops `serve` at startup.
+	if sessionStore := server.SessionStore(); sessionStore != nil {
+		server.Use(SessionManagerWithStore(config, sessionStore))
+	}
+
 	server.Use(Recovery(logger))
 
 	command := NewRootCommand()
@@ -62,8 +72,10 @@ func NewApp(asset *Asset, viewFuncs map[string]interface{}) *App {
 	command.AddCommand(newDBCreateCommand(config, dbManager, logger))
 	command.AddCommand(newDBDropCommand(config, dbManager, logger))
 	command.AddCommand(newDBMigrateCommand(config, dbManager, logger))
+	command.AddCommand(newDBMigrateRedoCommand(config, dbManager, logger))
 	command.AddCommand(newDBMigrateStatusCommand(config, dbManager, logger))
 	command.AddCommand(newDBRollbackCommand(config, dbManager, logger))
+	command.AddCommand(newDBSchemaCheckCommand(config, dbManager, logger))
 	command.AddCommand(newDBSchemaLoadCommand(config, dbManager, logger))
 	command.AddCommand(newDBSeedCommand(config, dbManager, logger))
 	command.AddCommand(newDcDownCommand(asset, logger))
@@ -72,11 +84,12 @@ func NewApp(asset *Asset, viewFuncs map[string]interface{}) *App {
 	command.AddCommand(newMiddlewareCommand(config, logger, server))
 	command.AddCommand(newRoutesCommand(config, logger, server))
 	command.AddCommand(newSecretCommand(logger))
-	command.AddCommand(newServeCommand(dbManager, logger, server))
+	command.AddCommand(newServeCommand(dbManager, logger, mailer, server))
 	command.AddCommand(newSetupCommand(asset, config, dbManager, logger))
 	command.AddCommand(newSSLSetupCommand(logger, server))
 	command.AddCommand(newSSLTeardownCommand(logger, server))
 	command.AddCommand(newTeardownCommand(asset, config, dbManager, logger))
+	command.AddCommand(newWorkCommand(dbManager, logger, mailer, worker))
 
 	if IsDebugBuild() {
 		command.AddCommand(newBuildCommand(asset, logger, server))
@@ -96,6 +109,7 @@ func NewApp(asset *Asset, viewFuncs map[string]interface{}) *App {
 		server:     server,
 		support:    support,
 		viewEngine: viewEngine,
+		worker:     worker,
 	}
 }
 
@@ -149,6 +163,11 @@ func (a *App) ViewEngine() *ViewEngine {
 	return a.viewEngine
 }
 
+// Worker returns the app instance's worker.
+func (a *App) Worker() *Worker {
+	return a.worker
+}
+
 // Run starts running the app instance.
 func (a *App) Run() error {
 	a.mailer.SetupPreview()
@@ -19,11 +19,13 @@ func NewAsset(embedded http.FileSystem, layout map[string]string, moduleRoot str
 	asset := &Asset{
 		embedded: embedded,
 		layout: map[string]string{
-			"config": "configs",
-			"docker": ".docker",
-			"locale": "pkg/locales",
-			"view":   "pkg/views",
-			"web":    "web",
+			"config":   "configs",
+			"docker":   ".docker",
+			"fixtures": "db/fixtures",
+			"locale":   "pkg/locales",
+			"migrate":  "db/migrate",
+			"view":     "pkg/views",
+			"web":      "web",
 		},
 		moduleRoot: moduleRoot,
 	}
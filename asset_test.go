@@ -16,7 +16,9 @@ func (s *AssetSuite) TestNewAsset() {
 
 	s.Equal("configs", asset.Layout()["config"])
 	s.Equal(".docker", asset.Layout()["docker"])
+	s.Equal("db/fixtures", asset.Layout()["fixtures"])
 	s.Equal("pkg/locales", asset.Layout()["locale"])
+	s.Equal("db/migrate", asset.Layout()["migrate"])
 	s.Equal("pkg/views", asset.Layout()["view"])
 	s.Equal("web", asset.Layout()["web"])
 
@@ -40,7 +42,9 @@ func (s *AssetSuite) TestNewAssetWithModuleRoot() {
 
 	s.Equal("appist/appist/configs", asset.Layout()["config"])
 	s.Equal("appist/appist/.docker", asset.Layout()["docker"])
+	s.Equal("appist/appist/db/fixtures", asset.Layout()["fixtures"])
 	s.Equal("appist/appist/pkg/locales", asset.Layout()["locale"])
+	s.Equal("appist/appist/db/migrate", asset.Layout()["migrate"])
 	s.Equal("appist/appist/pkg/views", asset.Layout()["view"])
 	s.Equal("appist/appist/web", asset.Layout()["web"])
 }
@@ -16,13 +16,18 @@ func newServer() *Server {
 		"locale": "testdata/app/pkg/locales",
 		"view":   "testdata/app/pkg/views",
 		"web":    "testdata/config/web",
-	})
+	}, "")
 	support := &Support{}
 	logger, _, _ := NewFakeLogger()
 	config := NewConfig(asset, logger, support)
 	i18n := NewI18n(asset, config, logger)
 	server := NewServer(asset, config, logger, support)
-	mailer := NewMailer(asset, config, i18n, logger, server, nil)
+	server.setupSessionStore(nil)
+	if len(server.Errors()) > 0 {
+		panic(server.Errors()[0])
+	}
+
+	mailer := NewMailer(asset, config, i18n, logger, server, nil, nil)
 
 	server.Use(AttachLogger(logger))
 	server.Use(AttachI18n(i18n))
@@ -37,7 +42,7 @@ func newServer() *Server {
 	server.Use(CSRF(config, logger, support))
 	server.Use(Secure(config))
 	server.Use(APIOnlyResponse())
-	server.Use(SessionManager(config))
+	server.Use(SessionManagerWithStore(config, server.SessionStore()))
 	server.Use(Recovery(logger))
 
 	return server
@@ -48,7 +53,7 @@ func testRequest(B *testing.B, server *Server, method, path string) {
 	B.ResetTimer()
 
 	for i := 0; i < B.N; i++ {
-		server.TestHTTPRequest("GET", "/ping", nil, nil)
+		server.TestHTTPRequest(method, path, nil, nil)
 	}
 }
 
@@ -109,3 +114,52 @@ func BenchmarkServerParamWrite(B *testing.B) {
 	})
 	testRequest(B, server, "GET", "/user/gordon")
 }
+
+// BenchmarkSessionStorePerRequest creates the session store for every request which is how the SessionManager used
+// to work. Run it with HTTP_SESSION_PROVIDER=redis to see the cost of creating and pinging the redis pool.
+func BenchmarkSessionStorePerRequest(B *testing.B) {
+	os.Setenv("APPY_MASTER_KEY", "481e5d98a31585148b8b1dfb6a3c0465")
+	os.Setenv("HTTP_CSRF_SECRET", "481e5d98a31585148b8b1dfb6a3c0465")
+	os.Setenv("HTTP_SESSION_SECRETS", "481e5d98a31585148b8b1dfb6a3c0465")
+	defer func() {
+		os.Unsetenv("HTTP_CSRF_SECRET")
+		os.Unsetenv("HTTP_SESSION_SECRETS")
+	}()
+
+	server := newServer()
+	server.GET("/session", func(c *Context) {
+		sessionStore, err := newSessionStore(server.Config(), nil)
+		if err != nil {
+			B.Fatal(err)
+		}
+		if closer, ok := sessionStore.(io.Closer); ok {
+			defer closer.Close()
+		}
+
+		session := &Session{name: server.Config().HTTPSessionName, request: c.Request, store: sessionStore, writer: c.Writer}
+		session.Set("username", "gordon")
+		session.Save()
+	})
+	testRequest(B, server, "GET", "/session")
+}
+
+// BenchmarkSessionStoreShared reuses the server's session store across the requests.
+func BenchmarkSessionStoreShared(B *testing.B) {
+	os.Setenv("APPY_MASTER_KEY", "481e5d98a31585148b8b1dfb6a3c0465")
+	os.Setenv("HTTP_CSRF_SECRET", "481e5d98a31585148b8b1dfb6a3c0465")
+	os.Setenv("HTTP_SESSION_SECRETS", "481e5d98a31585148b8b1dfb6a3c0465")
+	defer func() {
+		os.Unsetenv("HTTP_CSRF_SECRET")
+		os.Unsetenv("HTTP_SESSION_SECRETS")
+	}()
+
+	server := newServer()
+	defer server.CloseSessionStore()
+
+	server.GET("/session", func(c *Context) {
+		session := c.Session()
+		session.Set("username", "gordon")
+		session.Save()
+	})
+	testRequest(B, server, "GET", "/session")
+}
@@ -76,6 +76,16 @@ func newBuildCommand(asset *Asset, logger *Logger, server *Server) *Command {
 				logger.Fatal(err)
 			}
 
+			// Only the SQL migrations are read at runtime as the Go migrations are compiled into the binary.
+			if _, err := os.Stat(asset.Layout()["migrate"]); err == nil {
+				err = copy.Copy(asset.Layout()["migrate"], releasePath+"/"+asset.Layout()["migrate"], copy.Options{
+					Skip: func(src string) bool { return strings.HasSuffix(src, ".go") },
+				})
+				if err != nil {
+					logger.Fatal(err)
+				}
+			}
+
 			keyFiles, _ := filepath.Glob(releasePath + "/" + asset.Layout()["config"] + "/*.key")
 			for _, keyFile := range keyFiles {
 				os.Remove(keyFile)
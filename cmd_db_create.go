@@ -2,6 +2,8 @@
 
 package appy
 
+import "context"
+
 func newDBCreateCommand(config *Config, dbManager *DBManager, logger *Logger) *Command {
 	cmd := &Command{
 		Use:   "db:create",
@@ -36,7 +38,7 @@ func runDBCreateAll(config *Config, dbManager *DBManager, logger *Logger) {
 
 		targetDB := db.config.Database
 		db.config.Database = "postgres"
-		err := db.Connect()
+		err := dbManager.connect(context.Background(), name)
 		if err != nil {
 			logger.Fatal(err)
 		}
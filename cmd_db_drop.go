@@ -2,6 +2,8 @@
 
 package appy
 
+import "context"
+
 func newDBDropCommand(config *Config, dbManager *DBManager, logger *Logger) *Command {
 	cmd := &Command{
 		Use:   "db:drop",
@@ -40,7 +42,7 @@ func runDBDropAll(config *Config, dbManager *DBManager, logger *Logger) {
 
 		targetDB := db.config.Database
 		db.config.Database = "postgres"
-		err := db.Connect()
+		err := dbManager.connect(context.Background(), name)
 		if err != nil {
 			logger.Fatal(err)
 		}
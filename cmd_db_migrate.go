@@ -2,8 +2,10 @@
 
 package appy
 
+import "context"
+
 func newDBMigrateCommand(config *Config, dbManager *DBManager, logger *Logger) *Command {
-	var target string
+	var target, version string
 
 	cmd := &Command{
 		Use:   "db:migrate",
@@ -21,6 +23,10 @@ func newDBMigrateCommand(config *Config, dbManager *DBManager, logger *Logger) *
 				logger.Fatalf("No database is defined in 'configs/.env.%s'", config.AppyEnv)
 			}
 
+			if version != "" && target == "" {
+				logger.Fatal("Please specify the target database with --database when using --version")
+			}
+
 			if target != "" {
 				db := dbManager.DB(target)
 				if db == nil {
@@ -31,15 +37,20 @@ func newDBMigrateCommand(config *Config, dbManager *DBManager, logger *Logger) *
 					logger.Fatalf("Unable to run migration on '%s' database that is a replica", target)
 				}
 
+				if err := dbManager.connect(context.Background(), target); err != nil {
+					logger.Fatal(err)
+				}
+				defer dbManager.CloseAll(context.Background())
+
 				logger.Infof("Migrating '%s' database...", target)
 
-				err := db.Connect()
-				if err != nil {
-					logger.Fatal(err)
+				var err error
+				if version != "" {
+					err = db.MigrateTo(version)
+				} else {
+					err = db.Migrate()
 				}
-				defer db.Close()
 
-				err = db.Migrate()
 				if err != nil {
 					logger.Fatal(err)
 				}
@@ -66,10 +77,16 @@ func newDBMigrateCommand(config *Config, dbManager *DBManager, logger *Logger) *
 	}
 
 	cmd.Flags().StringVar(&target, "database", "", "The target database to migrate")
+	cmd.Flags().StringVar(&version, "version", "", "The target version to migrate up or down to (requires --database)")
 	return cmd
 }
 
 func runDBMigrateAll(config *Config, dbManager *DBManager, logger *Logger) {
+	if err := dbManager.connect(context.Background(), dbManager.primaryNames()...); err != nil {
+		logger.Fatal(err)
+	}
+	defer dbManager.CloseAll(context.Background())
+
 	for name, db := range dbManager.databases {
 		if db.Config().Replica {
 			continue
@@ -77,13 +94,7 @@ func runDBMigrateAll(config *Config, dbManager *DBManager, logger *Logger) {
 
 		logger.Infof("Migrating '%s' database...", name)
 
-		err := db.Connect()
-		if err != nil {
-			logger.Fatal(err)
-		}
-		defer db.Close()
-
-		err = db.Migrate()
+		err := db.Migrate()
 		if err != nil {
 			logger.Fatal(err)
 		}
@@ -0,0 +1,69 @@
+//+build !test
+
+package appy
+
+import "context"
+
+func newDBMigrateRedoCommand(config *Config, dbManager *DBManager, logger *Logger) *Command {
+	var (
+		step   int
+		target string
+	)
+
+	cmd := &Command{
+		Use:   "db:migrate:redo",
+		Short: "Rollback and migrate the database(default: primary, use --database to specify the target database) again for the current environment",
+		Run: func(cmd *Command, args []string) {
+			if len(config.Errors()) > 0 {
+				logger.Fatal(config.Errors()[0])
+			}
+
+			if len(dbManager.Errors()) > 0 {
+				logger.Fatal(dbManager.Errors()[0])
+			}
+
+			if len(dbManager.databases) < 1 {
+				logger.Fatalf("No database is defined in 'configs/.env.%s'", config.AppyEnv)
+			}
+
+			db := dbManager.DB(target)
+			if db == nil {
+				logger.Fatalf("No database called '%s' defined in 'configs/.env.%s'", target, config.AppyEnv)
+			}
+
+			if db.Config().Replica {
+				logger.Fatalf("Unable to run migration redo on '%s' database that is a replica", target)
+			}
+
+			if err := dbManager.connect(context.Background(), target); err != nil {
+				logger.Fatal(err)
+			}
+			defer dbManager.CloseAll(context.Background())
+
+			logger.Infof("Redoing migrations for '%s' database...", target)
+
+			err := db.MigrateRedo(step)
+			if err != nil {
+				logger.Fatal(err)
+			}
+
+			logger.Infof("Redoing migrations for '%s' database... DONE", target)
+
+			if IsDebugBuild() {
+				logger.Infof("")
+				logger.Infof("Dumping schema for '%s' database...", target)
+
+				err = db.DumpSchema(target)
+				if err != nil {
+					logger.Fatal(err)
+				}
+
+				logger.Infof("Dumping schema for '%s' database... DONE", target)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&target, "database", "primary", "The target database to redo the migrations")
+	cmd.Flags().IntVar(&step, "step", 1, "The number of migrations to redo")
+	return cmd
+}
@@ -3,6 +3,7 @@
 package appy
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/bndr/gotabulate"
@@ -27,17 +28,16 @@ func newDBMigrateStatusCommand(config *Config, dbManager *DBManager, logger *Log
 
 			logger.SetDBLogging(false)
 
+			if err := dbManager.connect(context.Background(), dbManager.primaryNames()...); err != nil {
+				logger.Fatal(err)
+			}
+			defer dbManager.CloseAll(context.Background())
+
 			for name, db := range dbManager.databases {
 				if db.Config().Replica {
 					continue
 				}
 
-				err := db.Connect()
-				if err != nil {
-					logger.Fatal(err)
-				}
-				defer db.Close()
-
 				fmt.Println()
 				fmt.Printf("database: %s\n", name)
 				fmt.Println()
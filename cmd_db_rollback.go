@@ -2,8 +2,13 @@
 
 package appy
 
+import "context"
+
 func newDBRollbackCommand(config *Config, dbManager *DBManager, logger *Logger) *Command {
-	var target string
+	var (
+		step   int
+		target string
+	)
 
 	cmd := &Command{
 		Use:   "db:rollback",
@@ -30,15 +35,14 @@ func newDBRollbackCommand(config *Config, dbManager *DBManager, logger *Logger)
 				logger.Fatalf("Unable to run rollback on '%s' database that is a replica", target)
 			}
 
-			logger.Infof("Rolling back '%s' database...", target)
-
-			err := db.Connect()
-			if err != nil {
+			if err := dbManager.connect(context.Background(), target); err != nil {
 				logger.Fatal(err)
 			}
-			defer db.Close()
+			defer dbManager.CloseAll(context.Background())
+
+			logger.Infof("Rolling back '%s' database...", target)
 
-			err = db.Rollback()
+			err := db.RollbackStep(step)
 			if err != nil {
 				logger.Fatal(err)
 			}
@@ -60,5 +64,6 @@ func newDBRollbackCommand(config *Config, dbManager *DBManager, logger *Logger)
 	}
 
 	cmd.Flags().StringVar(&target, "database", "primary", "The target database to rollback")
+	cmd.Flags().IntVar(&step, "step", 1, "The number of migrations to rollback")
 	return cmd
 }
@@ -0,0 +1,77 @@
+//+build !test
+
+package appy
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+func newDBSchemaCheckCommand(config *Config, dbManager *DBManager, logger *Logger) *Command {
+	return &Command{
+		Use:   "db:schema:check",
+		Short: "Check all the databases against the dumped schema and migrations for the current environment, and exit with non-zero code if there is any drift",
+		Run: func(cmd *Command, args []string) {
+			if len(config.Errors()) > 0 {
+				logger.Fatal(config.Errors()[0])
+			}
+
+			if len(dbManager.Errors()) > 0 {
+				logger.Fatal(dbManager.Errors()[0])
+			}
+
+			if len(dbManager.databases) < 1 {
+				logger.Fatalf("No database is defined in 'configs/.env.%s'", config.AppyEnv)
+			}
+
+			logger.SetDBLogging(false)
+
+			if err := dbManager.connect(context.Background(), dbManager.primaryNames()...); err != nil {
+				logger.Fatal(err)
+			}
+			defer dbManager.CloseAll(context.Background())
+
+			names := dbManager.primaryNames()
+			sort.Strings(names)
+
+			drifted := false
+			for _, name := range names {
+				// The seeds table and the postgres session store's table are created on demand instead of by a
+				// migration.
+				seedsTable := dbManager.DB(name).Config().SchemaSeedsTable
+				ignoredTables := []string{seedsTable[strings.LastIndex(seedsTable, ".")+1:]}
+				if config.HTTPSessionProvider == "postgres" && config.HTTPSessionPostgresDB == name {
+					table := config.HTTPSessionPostgresTable
+					ignoredTables = append(ignoredTables, table[strings.LastIndex(table, ".")+1:])
+				}
+
+				diff, err := dbManager.DB(name).CheckSchema(ignoredTables...)
+				if err != nil {
+					logger.Fatal(err)
+				}
+
+				fmt.Println()
+				fmt.Printf("database: %s\n", name)
+				fmt.Println()
+
+				if diff.IsEmpty() {
+					fmt.Println("  no drift detected")
+					continue
+				}
+
+				drifted = true
+				for _, line := range diff.Report() {
+					fmt.Printf("  %s\n", line)
+				}
+			}
+
+			if drifted {
+				dbManager.CloseAll(context.Background())
+				os.Exit(1)
+			}
+		},
+	}
+}
@@ -3,10 +3,13 @@
 package appy
 
 import (
+	"context"
 	"os/exec"
 )
 
 func newDBSchemaDumpCommand(config *Config, dbManager *DBManager, logger *Logger) *Command {
+	var format string
+
 	cmd := &Command{
 		Use:   "db:schema:dump",
 		Short: "Dump all the databases schema for the current environment (only available in debug build)",
@@ -24,10 +27,17 @@ func newDBSchemaDumpCommand(config *Config, dbManager *DBManager, logger *Logger
 				logger.Fatalf("No database is defined in 'configs/.env.%s'", config.AppyEnv)
 			}
 
-			_, err := exec.LookPath("pg_dump")
-			if err != nil {
+			if format == "pg_dump" {
+				_, err := exec.LookPath("pg_dump")
+				if err != nil {
+					logger.Fatal(err)
+				}
+			}
+
+			if err := dbManager.connect(context.Background(), dbManager.primaryNames()...); err != nil {
 				logger.Fatal(err)
 			}
+			defer dbManager.CloseAll(context.Background())
 
 			for name, db := range dbManager.databases {
 				if db.Config().Replica {
@@ -36,13 +46,12 @@ func newDBSchemaDumpCommand(config *Config, dbManager *DBManager, logger *Logger
 
 				logger.Infof("Dumping schema for '%s' database...", name)
 
-				err := db.Connect()
-				if err != nil {
-					logger.Fatal(err)
+				dumpFormat := format
+				if dumpFormat == "" {
+					dumpFormat = db.Config().SchemaDumpFormat
 				}
-				defer db.Close()
 
-				err = db.DumpSchema(name)
+				err := db.DumpSchemaWithFormat(name, dumpFormat)
 				if err != nil {
 					logger.Fatal(err)
 				}
@@ -52,5 +61,6 @@ func newDBSchemaDumpCommand(config *Config, dbManager *DBManager, logger *Logger
 		},
 	}
 
+	cmd.Flags().StringVar(&format, "format", "", "The schema dump format which can be 'native' or 'pg_dump' (default: DB_SCHEMA_DUMP_FORMAT_<NAME>)")
 	return cmd
 }
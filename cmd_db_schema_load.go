@@ -2,6 +2,8 @@
 
 package appy
 
+import "context"
+
 func newDBSchemaLoadCommand(config *Config, dbManager *DBManager, logger *Logger) *Command {
 	cmd := &Command{
 		Use:   "db:schema:load",
@@ -29,6 +31,11 @@ func newDBSchemaLoadCommand(config *Config, dbManager *DBManager, logger *Logger
 func runDBSchemaLoad(config *Config, dbManager *DBManager, logger *Logger) {
 	logger.SetDBLogging(false)
 
+	if err := dbManager.connect(context.Background(), dbManager.primaryNames()...); err != nil {
+		logger.Fatal(err)
+	}
+	defer dbManager.CloseAll(context.Background())
+
 	for name, db := range dbManager.databases {
 		if db.Config().Replica {
 			continue
@@ -36,13 +43,7 @@ func runDBSchemaLoad(config *Config, dbManager *DBManager, logger *Logger) {
 
 		logger.Infof("Loading schema for '%s' database...", name)
 
-		err := db.Connect()
-		if err != nil {
-			logger.Fatal(err)
-		}
-		defer db.Close()
-
-		_, err = db.Exec(db.Schema())
+		_, err := db.Exec(db.Schema())
 		if err != nil {
 			logger.Fatal(err)
 		}
@@ -2,10 +2,14 @@
 
 package appy
 
+import "context"
+
 func newDBSeedCommand(config *Config, dbManager *DBManager, logger *Logger) *Command {
-	return &Command{
+	var names []string
+
+	cmd := &Command{
 		Use:   "db:seed",
-		Short: "Seed all databases for the current environment",
+		Short: "Seed all databases(default: all seeds, use --name to specify the seeds) for the current environment",
 		Run: func(cmd *Command, args []string) {
 			if len(config.Errors()) > 0 {
 				logger.Fatal(config.Errors()[0])
@@ -19,26 +23,58 @@ func newDBSeedCommand(config *Config, dbManager *DBManager, logger *Logger) *Com
 				logger.Fatalf("No database is defined in 'configs/.env.%s'", config.AppyEnv)
 			}
 
-			runDBSeedAll(config, dbManager, logger)
+			runDBSeedAll(config, dbManager, logger, names...)
 		},
 	}
+
+	cmd.Flags().StringSliceVar(&names, "name", nil, "The seed names to run with their dependencies (e.g. --name users,posts)")
+
+	return cmd
 }
 
-func runDBSeedAll(config *Config, dbManager *DBManager, logger *Logger) {
+func runDBSeedAll(config *Config, dbManager *DBManager, logger *Logger, names ...string) {
+	found := map[string]bool{}
+	for _, db := range dbManager.databases {
+		for _, name := range db.seedNames() {
+			found[name] = true
+		}
+	}
+
+	for _, name := range names {
+		if !found[name] {
+			logger.Fatalf("No seed called '%s' is registered", name)
+		}
+	}
+
+	if err := dbManager.connect(context.Background(), dbManager.primaryNames()...); err != nil {
+		logger.Fatal(err)
+	}
+	defer dbManager.CloseAll(context.Background())
+
 	for name, db := range dbManager.databases {
 		if db.Config().Replica {
 			continue
 		}
 
-		logger.Infof("Seeding '%s' database...", name)
+		seedNames := names
+		if len(names) > 0 {
+			seedNames = []string{}
+			for _, seedName := range db.seedNames() {
+				for _, n := range names {
+					if n == seedName {
+						seedNames = append(seedNames, seedName)
+					}
+				}
+			}
 
-		err := db.Connect()
-		if err != nil {
-			logger.Fatal(err)
+			if len(seedNames) < 1 {
+				continue
+			}
 		}
-		defer db.Close()
 
-		err = db.Seed()
+		logger.Infof("Seeding '%s' database...", name)
+
+		err := db.Seed(seedNames...)
 		if err != nil {
 			logger.Fatal(err)
 		}
@@ -8,8 +8,8 @@ import (
 
 func newGenMigrationCommand(config *Config, dbManager *DBManager, logger *Logger, support Supporter) *Command {
 	var (
-		tx     bool
-		target string
+		sql, tx bool
+		target  string
 	)
 
 	cmd := &Command{
@@ -36,7 +36,12 @@ func newGenMigrationCommand(config *Config, dbManager *DBManager, logger *Logger
 				logger.Fatalf("No database called '%s' defined in 'configs/.env.%s'", target, config.AppyEnv)
 			}
 
-			err := db.GenerateMigration(args[0], target, tx)
+			generate := db.GenerateMigration
+			if sql {
+				generate = db.GenerateSQLMigration
+			}
+
+			err := generate(args[0], target, tx)
 			if err != nil {
 				logger.Fatal(err)
 			}
@@ -45,5 +50,6 @@ func newGenMigrationCommand(config *Config, dbManager *DBManager, logger *Logger
 
 	cmd.Flags().StringVar(&target, "database", "primary", "The target database to generate the migration file for")
 	cmd.Flags().BoolVar(&tx, "tx", true, "Indicate whether or not to run the migration in a transaction")
+	cmd.Flags().BoolVar(&sql, "sql", false, "Generate the '.up.sql' and '.down.sql' migration files instead of Go file")
 	return cmd
 }
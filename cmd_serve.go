@@ -11,7 +11,7 @@ import (
 	"syscall"
 )
 
-func newServeCommand(dbManager *DBManager, logger *Logger, server *Server) *Command {
+func newServeCommand(dbManager *DBManager, logger *Logger, mailer *Mailer, server *Server) *Command {
 	return &Command{
 		Use:   "serve",
 		Short: "Run the HTTP/HTTPS web server without `webpack-dev-server`",
@@ -24,6 +24,14 @@ func newServeCommand(dbManager *DBManager, logger *Logger, server *Server) *Comm
 				logger.Fatal(dbManager.Errors()[0])
 			}
 
+			if len(mailer.Errors()) > 0 {
+				logger.Fatal(mailer.Errors()[0])
+			}
+
+			if len(server.Errors()) > 0 {
+				logger.Fatal(server.Errors()[0])
+			}
+
 			if server.Config().HTTPSSLEnabled && !server.IsSSLCertExisted() {
 				logger.Fatal("HTTP_SSL_ENABLED is set to true without SSL certs, please generate using `go run . ssl:setup` first.")
 			}
@@ -43,13 +51,6 @@ func serve(dbManager *DBManager, logger *Logger, server *Server) {
 		<-httpQuit
 		logger.Infof("* Gracefully shutting down the server within %s...", server.Config().HTTPGracefulTimeout)
 
-		for _, db := range dbManager.databases {
-			err := db.Close()
-			if err != nil {
-				logger.Fatal(err)
-			}
-		}
-
 		// TODO: Allow graceful handling from the app.
 
 		ctx, cancel := context.WithTimeout(context.Background(), server.Config().HTTPGracefulTimeout)
@@ -65,14 +66,21 @@ func serve(dbManager *DBManager, logger *Logger, server *Server) {
 			}
 		}
 
-		close(httpDone)
-	}()
+		// Only close the session store and database pools after the in-flight HTTP requests are drained, and close the
+		// session store first as the postgres session store's cleanup still uses the database.
+		if err := server.CloseSessionStore(); err != nil {
+			logger.Fatal(err)
+		}
 
-	for _, db := range dbManager.databases {
-		err := db.Connect()
-		if err != nil {
+		if err := dbManager.CloseAll(ctx); err != nil {
 			logger.Fatal(err)
 		}
+
+		close(httpDone)
+	}()
+
+	if err := dbManager.ConnectAll(context.Background()); err != nil {
+		logger.Fatal(err)
 	}
 
 	for _, info := range server.Info() {
@@ -0,0 +1,64 @@
+//+build !test
+
+package appy
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+func newWorkCommand(dbManager *DBManager, logger *Logger, mailer *Mailer, worker *Worker) *Command {
+	return &Command{
+		Use:   "work",
+		Short: "Run the worker to process the background jobs",
+		Run: func(cmd *Command, args []string) {
+			if len(worker.Config().Errors()) > 0 {
+				logger.Fatal(worker.Config().Errors()[0])
+			}
+
+			if len(dbManager.Errors()) > 0 {
+				logger.Fatal(dbManager.Errors()[0])
+			}
+
+			if len(mailer.Errors()) > 0 {
+				logger.Fatal(mailer.Errors()[0])
+			}
+
+			work(dbManager, logger, worker)
+		},
+	}
+}
+
+func work(dbManager *DBManager, logger *Logger, worker *Worker) {
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt)
+	signal.Notify(quit, syscall.SIGTERM)
+
+	if err := dbManager.ConnectAll(context.Background()); err != nil {
+		logger.Fatal(err)
+	}
+
+	logger.Info(dbManager.Info())
+	logger.Info(worker.Info())
+	worker.Start()
+
+	<-quit
+	logger.Infof("* Gracefully shutting down the worker within %s...", worker.Config().WorkerGracefulTimeout)
+
+	ctx, cancel := context.WithTimeout(context.Background(), worker.Config().WorkerGracefulTimeout)
+	defer cancel()
+
+	if err := worker.Shutdown(ctx); err != nil {
+		logger.Error(err)
+	}
+
+	if err := worker.Close(); err != nil {
+		logger.Error(err)
+	}
+
+	if err := dbManager.CloseAll(ctx); err != nil {
+		logger.Error(err)
+	}
+}
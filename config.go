@@ -18,6 +18,10 @@ type (
 		AppyEnv   string `env:"APPY_ENV" envDefault:"development"`
 		AssetHost string `env:"ASSET_HOST" envDefault:""`
 
+		// DB related configuration.
+		DBSlowQueryThreshold time.Duration `env:"DB_SLOW_QUERY_THRESHOLD" envDefault:"200ms"`
+		DBNPlusOneThreshold  int           `env:"DB_N_PLUS_ONE_THRESHOLD" envDefault:"3"`
+
 		// GraphQL related configuration.
 		GQLPlaygroundEnabled          bool          `env:"GQL_PLAYGROUND_ENABLED" envDefault:"false"`
 		GQLPlaygroundPath             string        `env:"GQL_PLAYGROUND_PATH" envDefault:"/docs/graphql"`
@@ -57,9 +61,18 @@ type (
 		HTTPSessionRedisMaxConnLifetime time.Duration `env:"HTTP_SESSION_REDIS_MAX_CONN_LIFETIME" envDefault:"30s"`
 		HTTPSessionRedisWait            bool          `env:"HTTP_SESSION_REDIS_WAIT" envDefault:"true"`
 
+		// Session related configuration using postgres.
+		HTTPSessionPostgresDB              string        `env:"HTTP_SESSION_POSTGRES_DB" envDefault:"primary"`
+		HTTPSessionPostgresTable           string        `env:"HTTP_SESSION_POSTGRES_TABLE" envDefault:"sessions"`
+		HTTPSessionPostgresCleanupInterval time.Duration `env:"HTTP_SESSION_POSTGRES_CLEANUP_INTERVAL" envDefault:"5m"`
+
+		// Session related configuration using memory.
+		HTTPSessionMemoryMaxEntries int `env:"HTTP_SESSION_MEMORY_MAX_ENTRIES" envDefault:"10000"`
+
 		// Session related configuration.
 		HTTPSessionName       string        `env:"HTTP_SESSION_NAME" envDefault:"_session"`
 		HTTPSessionProvider   string        `env:"HTTP_SESSION_PROVIDER" envDefault:"cookie"`
+		HTTPSessionSerializer string        `env:"HTTP_SESSION_SERIALIZER" envDefault:""`
 		HTTPSessionExpiration int           `env:"HTTP_SESSION_EXPIRATION" envDefault:"1209600"`
 		HTTPSessionDomain     string        `env:"HTTP_SESSION_DOMAIN" envDefault:"localhost"`
 		HTTPSessionHTTPOnly   bool          `env:"HTTP_SESSION_HTTP_ONLY" envDefault:"true"`
@@ -98,12 +111,36 @@ type (
 		I18nDefaultLocale string `env:"I18N_DEFAULT_LOCALE" envDefault:"en"`
 
 		// Mailer related configuration.
-		MailerSMTPAddr              string `env:"MAILER_SMTP_ADDR" envDefault:""`
-		MailerSMTPPlainAuthIdentity string `env:"MAILER_SMTP_PLAIN_AUTH_IDENTITY" envDefault:""`
-		MailerSMTPPlainAuthUsername string `env:"MAILER_SMTP_PLAIN_AUTH_USERNAME" envDefault:""`
-		MailerSMTPPlainAuthPassword string `env:"MAILER_SMTP_PLAIN_AUTH_PASSWORD" envDefault:""`
-		MailerSMTPPlainAuthHost     string `env:"MAILER_SMTP_PLAIN_AUTH_HOST" envDefault:""`
-		MailerPreviewBaseURL        string `env:"MAILER_PREVIEW_BASE_URL" envDefault:"/appy/mailers"`
+		MailerTransport             string        `env:"MAILER_TRANSPORT" envDefault:"smtp"`
+		MailerSMTPAddr              string        `env:"MAILER_SMTP_ADDR" envDefault:""`
+		MailerSMTPDialTimeout       time.Duration `env:"MAILER_SMTP_DIAL_TIMEOUT" envDefault:"10s"`
+		MailerSMTPTLSMode           string        `env:"MAILER_SMTP_TLS_MODE" envDefault:"tls"`
+		MailerSMTPAuth              string        `env:"MAILER_SMTP_AUTH" envDefault:"plain"`
+		MailerSMTPPlainAuthIdentity string        `env:"MAILER_SMTP_PLAIN_AUTH_IDENTITY" envDefault:""`
+		MailerSMTPPlainAuthUsername string        `env:"MAILER_SMTP_PLAIN_AUTH_USERNAME" envDefault:""`
+		MailerSMTPPlainAuthPassword string        `env:"MAILER_SMTP_PLAIN_AUTH_PASSWORD" envDefault:""`
+		MailerSMTPPlainAuthHost     string        `env:"MAILER_SMTP_PLAIN_AUTH_HOST" envDefault:""`
+		MailerSendmailPath          string        `env:"MAILER_SENDMAIL_PATH" envDefault:"/usr/sbin/sendmail"`
+		MailerFilePath              string        `env:"MAILER_FILE_PATH" envDefault:"./tmp/mails"`
+		MailerPreviewBaseURL        string        `env:"MAILER_PREVIEW_BASE_URL" envDefault:"/appy/mailers"`
+
+		// Worker related configuration using redis pool.
+		WorkerRedisAddr            string        `env:"WORKER_REDIS_ADDR" envDefault:"localhost:6379"`
+		WorkerRedisAuth            string        `env:"WORKER_REDIS_AUTH" envDefault:""`
+		WorkerRedisDb              string        `env:"WORKER_REDIS_DB" envDefault:"0"`
+		WorkerRedisMaxActive       int           `env:"WORKER_REDIS_MAX_ACTIVE" envDefault:"64"`
+		WorkerRedisMaxIdle         int           `env:"WORKER_REDIS_MAX_IDLE" envDefault:"32"`
+		WorkerRedisIdleTimeout     time.Duration `env:"WORKER_REDIS_IDLE_TIMEOUT" envDefault:"30s"`
+		WorkerRedisMaxConnLifetime time.Duration `env:"WORKER_REDIS_MAX_CONN_LIFETIME" envDefault:"30s"`
+		WorkerRedisWait            bool          `env:"WORKER_REDIS_WAIT" envDefault:"true"`
+
+		// Worker related configuration.
+		WorkerConcurrency       int           `env:"WORKER_CONCURRENCY" envDefault:"25"`
+		WorkerQueues            []string      `env:"WORKER_QUEUES" envDefault:"default"`
+		WorkerMaxRetry          int           `env:"WORKER_MAX_RETRY" envDefault:"25"`
+		WorkerPollInterval      time.Duration `env:"WORKER_POLL_INTERVAL" envDefault:"1s"`
+		WorkerGracefulTimeout   time.Duration `env:"WORKER_GRACEFUL_TIMEOUT" envDefault:"30s"`
+		WorkerProcessingTimeout time.Duration `env:"WORKER_PROCESSING_TIMEOUT" envDefault:"30m"`
 
 		path      string
 		errors    []error
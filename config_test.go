@@ -38,77 +38,104 @@ func (s *ConfigSuite) TestNewConfig() {
 	}()
 
 	tt := map[string]interface{}{
-		"AppyEnv":                         "development",
-		"AssetHost":                       "",
-		"GQLPlaygroundEnabled":            false,
-		"GQLPlaygroundPath":               "/docs/graphql",
-		"GQLAPQCacheSize":                 100,
-		"GQLQueryCacheSize":               1000,
-		"GQLComplexityLimit":              100,
-		"GQLMultipartMaxMemory":           int64(0),
-		"GQLMultipartMaxUploadSize":       int64(0),
-		"GQLWebsocketKeepAliveDuration":   10 * time.Second,
-		"HTTPDebugEnabled":                false,
-		"HTTPGzipCompressLevel":           -1,
-		"HTTPGzipExcludedExts":            []string{},
-		"HTTPLogFilterParameters":         []string{"password"},
-		"HTTPHealthCheckURL":              "/health_check",
-		"HTTPHost":                        "localhost",
-		"HTTPPort":                        "3000",
-		"HTTPGracefulTimeout":             30 * time.Second,
-		"HTTPIdleTimeout":                 75 * time.Second,
-		"HTTPMaxHeaderBytes":              0,
-		"HTTPReadTimeout":                 60 * time.Second,
-		"HTTPReadHeaderTimeout":           60 * time.Second,
-		"HTTPWriteTimeout":                60 * time.Second,
-		"HTTPSSLEnabled":                  false,
-		"HTTPSSLCertPath":                 "./tmp/ssl",
-		"HTTPSessionRedisAddr":            "localhost:6379",
-		"HTTPSessionRedisAuth":            "",
-		"HTTPSessionRedisDb":              "0",
-		"HTTPSessionRedisMaxActive":       64,
-		"HTTPSessionRedisMaxIdle":         32,
-		"HTTPSessionRedisIdleTimeout":     30 * time.Second,
-		"HTTPSessionRedisMaxConnLifetime": 30 * time.Second,
-		"HTTPSessionRedisWait":            true,
-		"HTTPSessionName":                 "_session",
-		"HTTPSessionProvider":             "cookie",
-		"HTTPSessionSecrets":              [][]byte{},
-		"HTTPSessionDomain":               "localhost",
-		"HTTPSessionHTTPOnly":             true,
-		"HTTPSessionExpiration":           1209600,
-		"HTTPSessionPath":                 "/",
-		"HTTPSessionSecure":               false,
-		"HTTPAllowedHosts":                []string{},
-		"HTTPCSRFCookieDomain":            "localhost",
-		"HTTPCSRFCookieHTTPOnly":          true,
-		"HTTPCSRFCookieMaxAge":            0,
-		"HTTPCSRFCookieName":              "_csrf_token",
-		"HTTPCSRFCookiePath":              "/",
-		"HTTPCSRFCookieSecure":            false,
-		"HTTPCSRFFieldName":               "authenticity_token",
-		"HTTPCSRFRequestHeader":           "X-CSRF-Token",
-		"HTTPCSRFSecret":                  []byte{},
-		"HTTPSSLRedirect":                 false,
-		"HTTPSSLTemporaryRedirect":        false,
-		"HTTPSSLHost":                     "",
-		"HTTPSTSSeconds":                  int64(0),
-		"HTTPSTSIncludeSubdomains":        false,
-		"HTTPFrameDeny":                   false,
-		"HTTPCustomFrameOptionsValue":     "",
-		"HTTPContentTypeNosniff":          false,
-		"HTTPBrowserXSSFilter":            false,
-		"HTTPContentSecurityPolicy":       "",
-		"HTTPReferrerPolicy":              "",
-		"HTTPIENoOpen":                    false,
-		"HTTPSSLProxyHeaders":             map[string]string{"X-Forwarded-Proto": "https"},
-		"I18nDefaultLocale":               "en",
-		"MailerSMTPAddr":                  "",
-		"MailerSMTPPlainAuthIdentity":     "",
-		"MailerSMTPPlainAuthUsername":     "",
-		"MailerSMTPPlainAuthPassword":     "",
-		"MailerSMTPPlainAuthHost":         "",
-		"MailerPreviewBaseURL":            "/appy/mailers",
+		"AppyEnv":                            "development",
+		"AssetHost":                          "",
+		"DBSlowQueryThreshold":               200 * time.Millisecond,
+		"DBNPlusOneThreshold":                3,
+		"GQLPlaygroundEnabled":               false,
+		"GQLPlaygroundPath":                  "/docs/graphql",
+		"GQLAPQCacheSize":                    100,
+		"GQLQueryCacheSize":                  1000,
+		"GQLComplexityLimit":                 100,
+		"GQLMultipartMaxMemory":              int64(0),
+		"GQLMultipartMaxUploadSize":          int64(0),
+		"GQLWebsocketKeepAliveDuration":      10 * time.Second,
+		"HTTPDebugEnabled":                   false,
+		"HTTPGzipCompressLevel":              -1,
+		"HTTPGzipExcludedExts":               []string{},
+		"HTTPLogFilterParameters":            []string{"password"},
+		"HTTPHealthCheckURL":                 "/health_check",
+		"HTTPHost":                           "localhost",
+		"HTTPPort":                           "3000",
+		"HTTPGracefulTimeout":                30 * time.Second,
+		"HTTPIdleTimeout":                    75 * time.Second,
+		"HTTPMaxHeaderBytes":                 0,
+		"HTTPReadTimeout":                    60 * time.Second,
+		"HTTPReadHeaderTimeout":              60 * time.Second,
+		"HTTPWriteTimeout":                   60 * time.Second,
+		"HTTPSSLEnabled":                     false,
+		"HTTPSSLCertPath":                    "./tmp/ssl",
+		"HTTPSessionRedisAddr":               "localhost:6379",
+		"HTTPSessionRedisAuth":               "",
+		"HTTPSessionRedisDb":                 "0",
+		"HTTPSessionRedisMaxActive":          64,
+		"HTTPSessionRedisMaxIdle":            32,
+		"HTTPSessionRedisIdleTimeout":        30 * time.Second,
+		"HTTPSessionRedisMaxConnLifetime":    30 * time.Second,
+		"HTTPSessionRedisWait":               true,
+		"HTTPSessionPostgresDB":              "primary",
+		"HTTPSessionPostgresTable":           "sessions",
+		"HTTPSessionPostgresCleanupInterval": 5 * time.Minute,
+		"HTTPSessionMemoryMaxEntries":        10000,
+		"HTTPSessionName":                    "_session",
+		"HTTPSessionProvider":                "cookie",
+		"HTTPSessionSerializer":              "",
+		"HTTPSessionSecrets":                 [][]byte{},
+		"HTTPSessionDomain":                  "localhost",
+		"HTTPSessionHTTPOnly":                true,
+		"HTTPSessionExpiration":              1209600,
+		"HTTPSessionPath":                    "/",
+		"HTTPSessionSecure":                  false,
+		"HTTPAllowedHosts":                   []string{},
+		"HTTPCSRFCookieDomain":               "localhost",
+		"HTTPCSRFCookieHTTPOnly":             true,
+		"HTTPCSRFCookieMaxAge":               0,
+		"HTTPCSRFCookieName":                 "_csrf_token",
+		"HTTPCSRFCookiePath":                 "/",
+		"HTTPCSRFCookieSecure":               false,
+		"HTTPCSRFFieldName":                  "authenticity_token",
+		"HTTPCSRFRequestHeader":              "X-CSRF-Token",
+		"HTTPCSRFSecret":                     []byte{},
+		"HTTPSSLRedirect":                    false,
+		"HTTPSSLTemporaryRedirect":           false,
+		"HTTPSSLHost":                        "",
+		"HTTPSTSSeconds":                     int64(0),
+		"HTTPSTSIncludeSubdomains":           false,
+		"HTTPFrameDeny":                      false,
+		"HTTPCustomFrameOptionsValue":        "",
+		"HTTPContentTypeNosniff":             false,
+		"HTTPBrowserXSSFilter":               false,
+		"HTTPContentSecurityPolicy":          "",
+		"HTTPReferrerPolicy":                 "",
+		"HTTPIENoOpen":                       false,
+		"HTTPSSLProxyHeaders":                map[string]string{"X-Forwarded-Proto": "https"},
+		"I18nDefaultLocale":                  "en",
+		"MailerTransport":                    "smtp",
+		"MailerSMTPAddr":                     "",
+		"MailerSMTPDialTimeout":              10 * time.Second,
+		"MailerSMTPTLSMode":                  "tls",
+		"MailerSMTPAuth":                     "plain",
+		"MailerSMTPPlainAuthIdentity":        "",
+		"MailerSMTPPlainAuthUsername":        "",
+		"MailerSMTPPlainAuthPassword":        "",
+		"MailerSMTPPlainAuthHost":            "",
+		"MailerSendmailPath":                 "/usr/sbin/sendmail",
+		"MailerFilePath":                     "./tmp/mails",
+		"MailerPreviewBaseURL":               "/appy/mailers",
+		"WorkerRedisAddr":                    "localhost:6379",
+		"WorkerRedisAuth":                    "",
+		"WorkerRedisDb":                      "0",
+		"WorkerRedisMaxActive":               64,
+		"WorkerRedisMaxIdle":                 32,
+		"WorkerRedisIdleTimeout":             30 * time.Second,
+		"WorkerRedisMaxConnLifetime":         30 * time.Second,
+		"WorkerRedisWait":                    true,
+		"WorkerConcurrency":                  25,
+		"WorkerQueues":                       []string{"default"},
+		"WorkerMaxRetry":                     25,
+		"WorkerPollInterval":                 1 * time.Second,
+		"WorkerGracefulTimeout":              30 * time.Second,
+		"WorkerProcessingTimeout":            30 * time.Minute,
 	}
 
 	config := appy.NewConfig(s.asset, s.logger, s.support)
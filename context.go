@@ -2,6 +2,7 @@ package appy
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
@@ -67,6 +68,24 @@ func (c *Context) DeliverMail(mail Mail) error {
 	return mailer.(*Mailer).Deliver(mail)
 }
 
+// DeliverMailLater enqueues the email to be sent out via SMTP by the worker in the background.
+func (c *Context) DeliverMailLater(mail Mail, opts ...JobOption) (*Job, error) {
+	mailer, _ := c.Get(mailerCtxKey.String())
+
+	if mail.Locale == "" {
+		mail.Locale = c.Locale()
+	}
+
+	return mailer.(*Mailer).DeliverLater(mail, opts...)
+}
+
+// EnqueueJob enqueues the job to be processed by the worker in the background.
+func (c *Context) EnqueueJob(name string, payload interface{}, opts ...JobOption) (*Job, error) {
+	worker, _ := c.Get(workerCtxKey.String())
+
+	return worker.(*Worker).Enqueue(name, payload, opts...)
+}
+
 // HTML renders the HTTP template with the HTTP code and the "text/html" Content-Type header.
 func (c *Context) HTML(code int, name string, obj interface{}) {
 	ve, _ := c.Get(viewEngineCtxKey.String())
@@ -197,6 +216,34 @@ func (c *Context) T(key string, args ...interface{}) string {
 	return i18n.(*I18n).T(key, args...)
 }
 
+// Validate validates the model or the request binding struct, and returns the field error map translated with the
+// request locale, or nil if it is valid. For the API-only request, it also aborts the request with 422 and the field
+// error map rendered as JSON, e.g. `{"errors": {"email": ["email is required"]}}`.
+func (c *Context) Validate(obj interface{}) map[string][]string {
+	errs := c.ValidationErrors(ValidateStruct(obj))
+	if errs != nil && c.IsAPIOnly() {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, H{"errors": errs})
+	}
+
+	return errs
+}
+
+// ValidationErrors returns the field error map translated with the request locale if the error is returned by
+// ValidateStruct, DB.Save or the request binding's validation, or nil otherwise.
+func (c *Context) ValidationErrors(err error) map[string][]string {
+	var errs ValidationErrors
+	if !errors.As(newValidationErrors(err), &errs) {
+		return nil
+	}
+
+	var i18n *I18n
+	if val, exists := c.Get(i18nCtxKey.String()); exists {
+		i18n, _ = val.(*I18n)
+	}
+
+	return errs.Translate(i18n, c.Locale())
+}
+
 // DefaultHTML uses the gin's default HTML method which doesn't use Jet template engine and is only meant for internal
 // use.
 func (c *Context) defaultHTML(code int, name string, obj interface{}) {
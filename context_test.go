@@ -1,10 +1,13 @@
 package appy
 
 import (
+	"context"
 	"crypto/tls"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -73,7 +76,7 @@ func (s *ContextSuite) TestI18n() {
 
 func (s *ContextSuite) TestDeliverMail() {
 	s.config.AppyEnv = "test"
-	mailer := NewMailer(s.asset, s.config, s.i18n, s.logger, s.server, nil)
+	mailer := NewMailer(s.asset, s.config, s.i18n, s.logger, s.server, nil, nil)
 	c, _ := NewTestContext(httptest.NewRecorder())
 	c.Set(i18nCtxKey.String(), s.i18n)
 	c.Set(mailerCtxKey.String(), mailer)
@@ -94,6 +97,32 @@ func (s *ContextSuite) TestDeliverMail() {
 	s.Equal(1, len(mailer.Deliveries()))
 }
 
+func (s *ContextSuite) TestDeliverMailLater() {
+	s.config.AppyEnv = "test"
+	worker := NewWorker(s.config, s.logger)
+	defer worker.Close()
+
+	mailer := NewMailer(s.asset, s.config, s.i18n, s.logger, s.server, worker, nil)
+	c, _ := NewTestContext(httptest.NewRecorder())
+	c.Set(i18nCtxKey.String(), s.i18n)
+	c.Set(mailerCtxKey.String(), mailer)
+	c.SetLocale("zh-CN")
+
+	mail := Mail{
+		From:     "support@appist.io",
+		To:       []string{"jane@appist.io"},
+		Subject:  "mailers.user.verifyAccount.subject",
+		Template: "mailers/user/verify_account",
+	}
+	_, err := c.DeliverMailLater(mail)
+	s.Nil(err)
+	s.Equal(0, len(mailer.Deliveries()))
+
+	s.Nil(worker.Drain(context.Background()))
+	s.Equal(1, len(mailer.Deliveries()))
+	s.Equal("zh-CN", mailer.Deliveries()[0].Locale)
+}
+
 func (s *ContextSuite) TestHTML() {
 	server := NewServer(s.asset, s.config, s.logger, s.support)
 	server.Use(AttachLogger(s.logger))
@@ -144,6 +173,71 @@ func (s *ContextSuite) TestHTMLMissingTemplate() {
 	s.Equal(http.StatusInternalServerError, w.Code)
 }
 
+func (s *ContextSuite) TestValidate() {
+	type signUpParams struct {
+		Email    string `json:"email" binding:"required,email"`
+		Password string `json:"password" binding:"required,min=8"`
+	}
+
+	server := NewServer(s.asset, s.config, s.logger, s.support)
+	server.Use(AttachLogger(s.logger))
+	server.Use(AttachI18n(s.i18n))
+	server.POST("/bind", func(c *Context) {
+		var params signUpParams
+		if errs := c.ValidationErrors(c.ShouldBindJSON(&params)); errs != nil {
+			c.JSON(http.StatusUnprocessableEntity, H{"errors": errs})
+			return
+		}
+
+		c.JSON(http.StatusOK, H{})
+	})
+	server.POST("/validate", func(c *Context) {
+		params := signUpParams{Email: "john", Password: "secret"}
+		if errs := c.Validate(&params); errs != nil {
+			if !c.IsAPIOnly() {
+				c.String(http.StatusOK, strings.Join(errs["email"], ","))
+			}
+
+			return
+		}
+
+		c.JSON(http.StatusOK, H{})
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/bind", strings.NewReader(`{"password":"secret"}`))
+	server.ServeHTTP(w, req)
+
+	s.Equal(http.StatusUnprocessableEntity, w.Code)
+	s.Equal(`{"errors":{"email":["email is required"],"password":["password must be at least 8 characters"]}}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/bind", strings.NewReader(`{"email":"john@appist.io","password":"password"}`))
+	server.ServeHTTP(w, req)
+
+	s.Equal(http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/validate", nil)
+	req.Header.Add("Accept-Language", "zh-TW")
+	req.Header.Add("X-API-Only", "1")
+	server.ServeHTTP(w, req)
+
+	s.Equal(http.StatusUnprocessableEntity, w.Code)
+	s.Equal(`{"errors":{"email":["email必須是有效的電子郵件地址"],"password":["password至少需要8個字元"]}}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/validate", nil)
+	server.ServeHTTP(w, req)
+
+	s.Equal(http.StatusOK, w.Code)
+	s.Equal("email must be a valid email address", w.Body.String())
+
+	c, _ := NewTestContext(httptest.NewRecorder())
+	s.Nil(c.ValidationErrors(nil))
+	s.Nil(c.ValidationErrors(errors.New("not a validation error")))
+}
+
 func (s *ContextSuite) TestViewEngineWithDebugBuild() {
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest("GET", "/", nil)
@@ -3,6 +3,7 @@ package appy
 import (
 	"bytes"
 	"fmt"
+	"hash/fnv"
 	"io/ioutil"
 	"net/url"
 	"os"
@@ -10,6 +11,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -17,6 +19,7 @@ import (
 	"time"
 
 	"github.com/go-pg/pg/v9"
+	"github.com/go-pg/pg/v9/orm"
 )
 
 type (
@@ -24,21 +27,36 @@ type (
 	// multiple goroutines.
 	DB struct {
 		*pg.DB
+		asset      *Asset
 		config     *DBConfig
 		logger     *Logger
 		migrations []*DBMigration
 		mu         *sync.Mutex
 		schema     string
-		seed       func(*DBTx) error
+		seeds      []*dbSeed
 		support    Supporter
+		testTx     *dbTestTx
 	}
 
 	// DBConfig contains database connection options.
 	DBConfig struct {
 		pg.Options
-		Replica               bool
-		SchemaSearchPath      string
-		SchemaMigrationsTable string
+		ConnectMaxRetryBackoff     time.Duration
+		ConnectMinRetryBackoff     time.Duration
+		ConnectRetryTimeout        time.Duration
+		MigrationLockTimeout       time.Duration
+		Primary                    string
+		Replica                    bool
+		ReplicaHealthCheckInterval time.Duration
+		ReplicaSelector            string
+		SchemaDumpFormat           string
+		SchemaSearchPath           string
+		SchemaMigrationsTable      string
+		SchemaSeedsTable           string
+		SSLCert                    string
+		SSLKey                     string
+		SSLMode                    string
+		SSLRootCert                string
 	}
 
 	// DBConn represents a single database connection rather than a pool of database connections. Prefer running queries
@@ -60,6 +78,19 @@ type (
 		UpTx    func(*DBTx) error
 	}
 
+	// DBMigrationError indicates a migration failed to run up or down, i.e. `Direction` is "up" or "down". `Applied`
+	// contains the versions that were successfully migrated in the same direction before the failure, which are not
+	// reverted.
+	DBMigrationError struct {
+		Applied   []string
+		Direction string
+		Err       error
+		Version   string
+	}
+
+	// DBQuery is the go-pg's query builder.
+	DBQuery = orm.Query
+
 	// DBQueryEvent keeps the query event information.
 	DBQueryEvent = pg.QueryEvent
 
@@ -68,8 +99,29 @@ type (
 		Version string
 	}
 
+	// DBSeedOption configures how a seed should be registered.
+	DBSeedOption func(*dbSeed)
+
 	// DBTx is an in-progress database transaction. It is safe for concurrent use by multiple goroutines.
-	DBTx = pg.Tx
+	//
+	// A transaction must end with a call to Commit or Rollback. Within the test transaction started by TestSuite, it is
+	// a savepoint of the test transaction instead.
+	//
+	// Note that DBTx used to be an alias of go-pg's `*pg.Tx` and now wraps it so that it can be a savepoint, which is a
+	// breaking change: the migrations, seeds and model hooks still work with the `orm.DB` methods, e.g. Exec/Model,
+	// but the code that passes a `*pg.Tx` as a DBTx or uses its Prepare/Stmt needs to use Tx() instead.
+	DBTx struct {
+		orm.DB
+		savepoint string
+		tx        *pg.Tx
+	}
+
+	dbSeed struct {
+		dependsOn []string
+		envs      []string
+		name      string
+		seed      func(*DBTx) error
+	}
 )
 
 var (
@@ -91,13 +143,38 @@ var (
 	// DBScan returns ColumnScanner that copies the columns in the row into the values.
 	DBScan = pg.Scan
 
-	dbMigratePath = "db/migrate/"
+	dbMigratePath               = "db/migrate/"
+	dbMigrationLockPollInterval = 100 * time.Millisecond
+	dbSQLMigrationNoTxDirective = "-- appy:no-transaction"
 )
 
+// DBSeedName specifies the unique seed name that is tracked in DB_SCHEMA_SEEDS_TABLE_<NAME>.
+func DBSeedName(name string) DBSeedOption {
+	return func(seed *dbSeed) {
+		seed.name = name
+	}
+}
+
+// DBSeedEnvs specifies the APPY_ENV(s) that the seed runs in, e.g. "development" for the demo data. The seed runs in
+// all the environments if it is not specified.
+func DBSeedEnvs(envs ...string) DBSeedOption {
+	return func(seed *dbSeed) {
+		seed.envs = envs
+	}
+}
+
+// DBSeedDependsOn specifies the seed names that must run before the seed.
+func DBSeedDependsOn(names ...string) DBSeedOption {
+	return func(seed *dbSeed) {
+		seed.dependsOn = names
+	}
+}
+
 // NewDB initializes the DB handler that is used to connect to the database.
-func NewDB(config *DBConfig, logger *Logger, support Supporter) *DB {
+func NewDB(config *DBConfig, asset *Asset, logger *Logger, support Supporter) *DB {
 	return &DB{
 		nil,
+		asset,
 		config,
 		logger,
 		nil,
@@ -105,6 +182,7 @@ func NewDB(config *DBConfig, logger *Logger, support Supporter) *DB {
 		"",
 		nil,
 		support,
+		nil,
 	}
 }
 
@@ -120,6 +198,49 @@ func (db *DB) Connect() error {
 	db.DB = pg.Connect(&opts)
 	db.AddQueryHook(db.logger)
 
+	return db.Ping()
+}
+
+// Begin starts a transaction, or a savepoint within the test transaction started by TestSuite. Most callers should use
+// RunInTransaction instead.
+func (db *DB) Begin() (*DBTx, error) {
+	if db.DB == nil {
+		return nil, ErrDBNotConnected
+	}
+
+	if savepoint, ok := db.testTx.savepoint(); ok {
+		if _, err := db.DB.Exec("SAVEPOINT " + savepoint); err != nil {
+			return nil, err
+		}
+
+		return &DBTx{DB: db.DB, savepoint: savepoint}, nil
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	return &DBTx{DB: tx, tx: tx}, nil
+}
+
+// RunInTransaction runs the fn in a transaction which is rolled back if the fn returns an error or panics, otherwise
+// it is committed.
+func (db *DB) RunInTransaction(fn func(*DBTx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	return tx.RunInTransaction(fn)
+}
+
+// Ping verifies the connection to the database is still alive.
+func (db *DB) Ping() error {
+	if db.DB == nil {
+		return ErrDBNotConnected
+	}
+
 	_, err := db.Exec("SELECT 1 /* appy framework */")
 	return err
 }
@@ -164,21 +285,25 @@ func (db *DB) Drop() []error {
 	return errs
 }
 
-// DumpSchema uses `pg_dump` to dump the database schema.
+// DumpSchema dumps the database schema into `db/migrate/<name>/schema.go` with DB_SCHEMA_DUMP_FORMAT_<NAME>.
 func (db *DB) DumpSchema(name string) error {
+	return db.DumpSchemaWithFormat(name, db.config.SchemaDumpFormat)
+}
+
+// DumpSchemaWithFormat dumps the database schema into `db/migrate/<name>/schema.go` with the format which can be
+// "native" that introspects the database via `pg_catalog` or "pg_dump" that shells out to `pg_dump`.
+func (db *DB) DumpSchemaWithFormat(name, format string) error {
 	var (
-		outBytes bytes.Buffer
-		out      string
+		out string
+		err error
 	)
 
-	path := dbMigratePath + name
-	err := os.MkdirAll(path, os.ModePerm)
-	if err != nil {
-		return err
+	if format != "native" && format != "pg_dump" {
+		return fmt.Errorf("schema dump format '%s' is not supported", format)
 	}
 
-	// A trick to utilise url package to parse our database address in a form like 0.0.0.0:5432
-	u, err := url.Parse("https://" + db.config.Addr)
+	path := db.migratePath(name)
+	err = os.MkdirAll(path, os.ModePerm)
 	if err != nil {
 		return err
 	}
@@ -188,39 +313,16 @@ func (db *DB) DumpSchema(name string) error {
 		return err
 	}
 
-	_, err = exec.LookPath("pg_dump")
-	if err != nil {
-		return err
+	if format == "pg_dump" {
+		out, err = db.dumpSchemaPGDump()
+	} else {
+		out, err = db.dumpSchemaNative()
 	}
 
-	dumpArgs := []string{
-		"-s", "-x", "-O", "--no-comments",
-		"-d", db.config.Database,
-		"-n", db.config.SchemaSearchPath,
-		"-h", u.Hostname(),
-		"-p", u.Port(),
-		"-U", db.config.User,
-	}
-	dumpCmd := exec.Command("pg_dump", dumpArgs...)
-	dumpCmd.Env = os.Environ()
-	dumpCmd.Env = append(dumpCmd.Env, []string{"PGPASSWORD=" + db.config.Password}...)
-	dumpCmd.Stdout = &outBytes
-	dumpCmd.Stderr = os.Stderr
-
-	err = dumpCmd.Run()
 	if err != nil {
 		return err
 	}
 
-	out = outBytes.String()
-	out = regexp.MustCompile(`(?i)--\n-- postgresql database dump.*\n--\n\n`).ReplaceAllString(out, "")
-	out = regexp.MustCompile(`(?i)--\ dumped.*\n(\n)?`).ReplaceAllString(out, "")
-	out = regexp.MustCompile(`(?i)create\ extension`).ReplaceAllString(out, "CREATE EXTENSION IF NOT EXISTS")
-	out = regexp.MustCompile(`(?i)create\ schema`).ReplaceAllString(out, "CREATE SCHEMA IF NOT EXISTS")
-	out = regexp.MustCompile(`(?i)create\ sequence`).ReplaceAllString(out, "CREATE SEQUENCE IF NOT EXISTS")
-	out = regexp.MustCompile(`(?i)create\ table`).ReplaceAllString(out, "CREATE TABLE IF NOT EXISTS")
-	out = strings.Trim(out, "\n")
-
 	var schemaMigrations []SchemaMigration
 	_, err = db.Query(
 		&schemaMigrations,
@@ -262,7 +364,7 @@ func (db *DB) DumpSchema(name string) error {
 
 // GenerateMigration generates the migration file for the target database.
 func (db *DB) GenerateMigration(name, target string, tx bool) error {
-	path := dbMigratePath + target
+	path := db.migratePath(target)
 	err := os.MkdirAll(path, os.ModePerm)
 	if err != nil {
 		return err
@@ -286,68 +388,67 @@ func (db *DB) GenerateMigration(name, target string, tx bool) error {
 	return nil
 }
 
-// Migrate runs migrations for the current environment that have not run yet.
-func (db *DB) Migrate() error {
-	db.mu.Lock()
-	defer db.mu.Unlock()
-
-	err := db.ensureSchemaMigrationsTable()
+// GenerateSQLMigration generates the `<version>_<name>.up.sql` and `<version>_<name>.down.sql` migration files for
+// the target database.
+func (db *DB) GenerateSQLMigration(name, target string, tx bool) error {
+	path := db.migratePath(target)
+	err := os.MkdirAll(path, os.ModePerm)
 	if err != nil {
 		return err
 	}
 
-	tx, err := db.Begin()
-	if err != nil {
-		return err
+	ts := time.Now()
+	prefix := path + "/" + ts.Format("20060102150405") + "_" + db.support.ToSnakeCase(name)
+	db.logger.Infof("Generating migration '%s' for '%s' database...", prefix+".{up,down}.sql", target)
+
+	content := []byte{}
+	if !tx {
+		content = []byte(dbSQLMigrationNoTxDirective + "\n")
 	}
 
-	defer func() {
-		if err = tx.Close(); err != nil {
-			db.logger.Fatal(err)
+	for _, fn := range []string{prefix + ".up.sql", prefix + ".down.sql"} {
+		err = ioutil.WriteFile(fn, content, 0644)
+		if err != nil {
+			return err
 		}
-	}()
-
-	migratedVersions, err := db.migratedVersions(tx)
-	if err != nil {
-		return err
 	}
 
-	for _, m := range db.migrations {
-		if !db.support.ArrayContains(migratedVersions, m.Version) {
-			if m.UpTx != nil {
-				err = m.UpTx(tx)
-				if err != nil {
-					_ = tx.Rollback()
-					return err
-				}
-
-				err = db.addSchemaMigration(tx, m)
-				if err != nil {
-					_ = tx.Rollback()
-					return err
-				}
-
-				err = tx.Commit()
-				if err != nil {
-					return err
-				}
-
-				continue
-			}
+	db.logger.Infof("Generating migration '%s' for '%s' database... DONE", prefix+".{up,down}.sql", target)
+	return nil
+}
 
-			err = m.Up(db)
-			if err != nil {
-				return err
-			}
+// Migrate runs migrations for the current environment that have not run yet. It holds the PostgreSQL advisory lock
+// so that only 1 migrator can run at a time across multiple app instances.
+func (db *DB) Migrate() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
 
-			err = db.addSchemaMigration(nil, m)
-			if err != nil {
-				return err
-			}
+	return db.withMigrationLock(func() error {
+		return db.migrateTo("")
+	})
+}
+
+// MigrateRedo rolls back the last N migrations and migrates them up again for the current environment.
+func (db *DB) MigrateRedo(step int) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	return db.withMigrationLock(func() error {
+		migratedVersions, err := db.migratedVersions()
+		if err != nil {
+			return err
 		}
-	}
 
-	return nil
+		if len(migratedVersions) < 1 {
+			return nil
+		}
+
+		if err := db.rollbackStep(step); err != nil {
+			return err
+		}
+
+		return db.migrateTo(migratedVersions[len(migratedVersions)-1])
+	})
 }
 
 // MigrateStatus returns the migration status for the current environment.
@@ -355,24 +456,19 @@ func (db *DB) MigrateStatus() ([][]string, error) {
 	db.mu.Lock()
 	defer db.mu.Unlock()
 
-	err := db.ensureSchemaMigrationsTable()
-	if err != nil {
-		return nil, err
-	}
-
-	tx, err := db.Begin()
-	if err != nil {
-		return nil, err
-	}
+	var status [][]string
+	err := db.withMigrationLock(func() error {
+		var err error
+		status, err = db.migrateStatus()
+		return err
+	})
 
-	defer func() {
-		if err = tx.Close(); err != nil {
-			db.logger.Fatal(err)
-		}
-	}()
+	return status, err
+}
 
+func (db *DB) migrateStatus() ([][]string, error) {
 	var migrationStatus [][]string
-	migratedVersions, err := db.migratedVersions(tx)
+	migratedVersions, err := db.migratedVersions()
 	if err != nil {
 		return nil, err
 	}
@@ -387,14 +483,21 @@ func (db *DB) MigrateStatus() ([][]string, error) {
 		migrationStatus = append(migrationStatus, []string{status, m.Version, strings.ReplaceAll(m.File, wd+"/", "")})
 	}
 
-	err = tx.Commit()
-	if err != nil {
-		return nil, err
-	}
-
 	return migrationStatus, nil
 }
 
+// MigrateTo migrates up or down to the specific version for the current environment, i.e. the migrations that are
+// newer than the version are rolled back and the pending migrations that are older than or equal to the version are
+// run.
+func (db *DB) MigrateTo(version string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	return db.withMigrationLock(func() error {
+		return db.migrateTo(version)
+	})
+}
+
 // RegisterMigration registers the up/down migrations that won't be executed in transaction.
 func (db *DB) RegisterMigration(up func(*DB) error, down func(*DB) error, args ...string) {
 	err := db.registerMigration(up, down, nil, nil, args...)
@@ -411,19 +514,36 @@ func (db *DB) RegisterMigrationTx(upTx func(*DBTx) error, downTx func(*DBTx) err
 	}
 }
 
-// RegisterSeedTx registers the seeding that will be executed in transaction.
-func (db *DB) RegisterSeedTx(seed func(*DBTx) error) {
-	db.seed = seed
+// RegisterSeedTx registers the seed that will be executed in its own transaction. The seed is named "default" unless
+// DBSeedName is specified, and each name can only be registered once.
+func (db *DB) RegisterSeedTx(seed func(*DBTx) error, opts ...DBSeedOption) {
+	err := db.addSeed(seed, opts...)
+	if err != nil {
+		db.logger.Fatal(err)
+	}
 }
 
-// Rollback rolls back the last migration for the current environment.
+// Rollback rolls back the last migration for the current environment. It holds the PostgreSQL advisory lock so that
+// only 1 migrator can run at a time across multiple app instances.
 func (db *DB) Rollback() error {
+	return db.RollbackStep(1)
+}
+
+// RollbackStep rolls back the last N migrations for the current environment.
+func (db *DB) RollbackStep(step int) error {
 	db.mu.Lock()
 	defer db.mu.Unlock()
 
-	err := db.ensureSchemaMigrationsTable()
-	if err != nil {
-		return err
+	return db.withMigrationLock(func() error {
+		return db.rollbackStep(step)
+	})
+}
+
+// RunInTestTx runs the fn in a transaction that is always rolled back afterwards so that the fixtures and the models
+// created by the factories in the fn don't leak into the other tests.
+func (db *DB) RunInTestTx(fn func(tx *DBTx) error) error {
+	if db.DB == nil {
+		return ErrDBNotConnected
 	}
 
 	tx, err := db.Begin()
@@ -432,58 +552,10 @@ func (db *DB) Rollback() error {
 	}
 
 	defer func() {
-		if err = tx.Close(); err != nil {
-			db.logger.Fatal(err)
-		}
+		_ = tx.Rollback()
 	}()
 
-	migratedVersions, err := db.migratedVersions(tx)
-	if err != nil {
-		return err
-	}
-
-	if len(migratedVersions) > 0 {
-		for i := len(db.migrations) - 1; i > -1; i-- {
-			m := db.migrations[i]
-
-			if migratedVersions[len(migratedVersions)-1] == m.Version {
-				if m.DownTx != nil {
-					err = m.DownTx(tx)
-					if err != nil {
-						_ = tx.Rollback()
-						return err
-					}
-
-					err = db.removeSchemaMigration(tx, m)
-					if err != nil {
-						_ = tx.Rollback()
-						return err
-					}
-
-					err = tx.Commit()
-					if err != nil {
-						return err
-					}
-
-					continue
-				}
-
-				err = m.Down(db)
-				if err != nil {
-					return err
-				}
-
-				err = db.removeSchemaMigration(nil, m)
-				if err != nil {
-					return err
-				}
-
-				break
-			}
-		}
-	}
-
-	return nil
+	return fn(tx)
 }
 
 // Schema returns the database schema.
@@ -496,40 +568,101 @@ func (db *DB) SetSchema(schema string) {
 	db.schema = schema
 }
 
-// Seed runs the seeding for the current environment.
-func (db *DB) Seed() error {
-	tx, err := db.Begin()
+// Seed runs the seeds for the current environment in the dependency order, or only the seeds with the names and
+// their dependencies if specified. Each seed runs in its own transaction which also records the seed name in
+// DB_SCHEMA_SEEDS_TABLE_<NAME> so that it is skipped on the subsequent runs.
+func (db *DB) Seed(names ...string) error {
+	if db.DB == nil {
+		return ErrDBNotConnected
+	}
+
+	seeds, err := db.sortSeeds(names...)
 	if err != nil {
 		return err
 	}
 
-	defer func() {
-		if err = tx.Close(); err != nil {
-			db.logger.Fatal(err)
+	if err := db.ensureSchemaSeedsTable(); err != nil {
+		return err
+	}
+
+	var seededNames []string
+	_, err = db.Query(
+		&seededNames,
+		`SELECT name FROM ?.?`,
+		DBSafeQuery(db.config.SchemaSearchPath),
+		DBSafeQuery(db.config.SchemaSeedsTable),
+	)
+	if err != nil {
+		return err
+	}
+
+	seeded := map[string]bool{}
+	for _, name := range seededNames {
+		seeded[name] = true
+	}
+
+	for _, seed := range seeds {
+		if seeded[seed.name] || !seed.runsIn(os.Getenv("APPY_ENV")) {
+			continue
 		}
-	}()
 
-	if db.seed != nil {
-		err := db.seed(tx)
-		if err != nil {
-			_ = tx.Rollback()
+		err := db.RunInTransaction(func(tx *DBTx) error {
+			if err := seed.seed(tx); err != nil {
+				return err
+			}
+
+			_, err := tx.Exec(
+				`INSERT INTO ?.? (name) VALUES (?)`,
+				DBSafeQuery(db.config.SchemaSearchPath),
+				DBSafeQuery(db.config.SchemaSeedsTable),
+				seed.name,
+			)
+
 			return err
+		})
+		if err != nil {
+			return fmt.Errorf("seeding '%s' failed: %s", seed.name, err)
 		}
 	}
 
-	err = tx.Commit()
-	if err != nil {
-		return err
+	return nil
+}
+
+func (db *DB) addSeed(seed func(*DBTx) error, opts ...DBSeedOption) error {
+	newSeed := &dbSeed{name: "default", seed: seed}
+	for _, opt := range opts {
+		opt(newSeed)
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for _, s := range db.seeds {
+		if s.name == newSeed.name {
+			return fmt.Errorf("seed '%s' is already registered", newSeed.name)
+		}
 	}
 
+	db.seeds = append(db.seeds, newSeed)
 	return nil
 }
 
-func (db *DB) addMigration(newMigration *DBMigration) {
+func (db *DB) addMigration(newMigration *DBMigration) error {
 	db.mu.Lock()
 	defer db.mu.Unlock()
 
+	for _, m := range db.migrations {
+		if m.Version == newMigration.Version {
+			return fmt.Errorf("migration version '%s' is already registered by '%s'", m.Version, m.File)
+		}
+	}
+
 	db.migrations = append(db.migrations, newMigration)
+	sort.SliceStable(db.migrations, func(i, j int) bool {
+		return db.migrations[i].Version < db.migrations[j].Version
+	})
+
+	return nil
 }
 
 func (db *DB) addSchemaMigration(tx *DBTx, targetMigration *DBMigration) error {
@@ -555,6 +688,190 @@ func (db *DB) addSchemaMigration(tx *DBTx, targetMigration *DBMigration) error {
 	return err
 }
 
+// withMigrationLock runs fn while holding the session-level PostgreSQL advisory lock that is keyed by the database
+// and DB_SCHEMA_MIGRATIONS_TABLE_<NAME>. It keeps trying to acquire the lock until DB_MIGRATION_LOCK_TIMEOUT_<NAME>
+// is reached.
+func (db *DB) withMigrationLock(fn func() error) error {
+	if db.DB == nil {
+		return ErrDBNotConnected
+	}
+
+	conn := db.Conn()
+	defer conn.Close()
+
+	key := db.migrationLockKey()
+	deadline := time.Now().Add(db.config.MigrationLockTimeout)
+	for {
+		var locked bool
+		_, err := conn.QueryOne(pg.Scan(&locked), `SELECT pg_try_advisory_lock(?)`, key)
+		if err != nil {
+			return err
+		}
+
+		if locked {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf(
+				"unable to acquire the migration lock on '%s' database within %s: %w",
+				db.config.Database, db.config.MigrationLockTimeout, ErrDBMigrationLocked,
+			)
+		}
+
+		time.Sleep(dbMigrationLockPollInterval)
+	}
+
+	defer func() {
+		if _, err := conn.Exec(`SELECT pg_advisory_unlock(?)`, key); err != nil {
+			db.logger.Error(err)
+		}
+	}()
+
+	return fn()
+}
+
+func (db *DB) migrationLockKey() int64 {
+	h := fnv.New64a()
+	h.Write([]byte(db.config.Database + "." + db.config.SchemaSearchPath + "." + db.config.SchemaMigrationsTable))
+
+	return int64(h.Sum64())
+}
+
+// dumpSchemaPGDump uses `pg_dump` to dump the database schema.
+func (db *DB) dumpSchemaPGDump() (string, error) {
+	var outBytes bytes.Buffer
+
+	// A trick to utilise url package to parse our database address in a form like 0.0.0.0:5432
+	u, err := url.Parse("https://" + db.config.Addr)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = exec.LookPath("pg_dump")
+	if err != nil {
+		return "", err
+	}
+
+	dumpArgs := []string{
+		"-s", "-x", "-O", "--no-comments",
+		"-d", db.config.Database,
+		"-n", db.config.SchemaSearchPath,
+		"-h", u.Hostname(),
+		"-p", u.Port(),
+		"-U", db.config.User,
+	}
+	dumpCmd := exec.Command("pg_dump", dumpArgs...)
+	dumpCmd.Env = os.Environ()
+	dumpCmd.Env = append(dumpCmd.Env, []string{"PGPASSWORD=" + db.config.Password}...)
+	dumpCmd.Stdout = &outBytes
+	dumpCmd.Stderr = os.Stderr
+
+	err = dumpCmd.Run()
+	if err != nil {
+		return "", err
+	}
+
+	out := outBytes.String()
+	out = regexp.MustCompile(`(?i)--\n-- postgresql database dump.*\n--\n\n`).ReplaceAllString(out, "")
+	out = regexp.MustCompile(`(?i)--\ dumped.*\n(\n)?`).ReplaceAllString(out, "")
+	out = regexp.MustCompile(`(?i)create\ extension`).ReplaceAllString(out, "CREATE EXTENSION IF NOT EXISTS")
+	out = regexp.MustCompile(`(?i)create\ schema`).ReplaceAllString(out, "CREATE SCHEMA IF NOT EXISTS")
+	out = regexp.MustCompile(`(?i)create\ sequence`).ReplaceAllString(out, "CREATE SEQUENCE IF NOT EXISTS")
+	out = regexp.MustCompile(`(?i)create\ table`).ReplaceAllString(out, "CREATE TABLE IF NOT EXISTS")
+
+	return strings.Trim(out, "\n"), nil
+}
+
+func (db *DB) seedNames() []string {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	names := []string{}
+	for _, seed := range db.seeds {
+		names = append(names, seed.name)
+	}
+
+	return names
+}
+
+// sortSeeds returns the seeds with the names, or all the seeds if no name is given, and their dependencies that are
+// sorted so that each seed comes after its dependencies and the seeds are in the registration order otherwise.
+func (db *DB) sortSeeds(names ...string) ([]*dbSeed, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	seedsByName := map[string]*dbSeed{}
+	for _, seed := range db.seeds {
+		seedsByName[seed.name] = seed
+	}
+
+	if len(names) < 1 {
+		for _, seed := range db.seeds {
+			names = append(names, seed.name)
+		}
+	}
+
+	sorted := []*dbSeed{}
+	visited := map[string]bool{}
+	visiting := map[string]bool{}
+
+	var visit func(name, dependent string) error
+	visit = func(name, dependent string) error {
+		seed, ok := seedsByName[name]
+		if !ok {
+			if dependent != "" {
+				return fmt.Errorf("seed '%s' depends on '%s' which is not found", dependent, name)
+			}
+
+			return fmt.Errorf("seed '%s' is not found", name)
+		}
+
+		if visited[name] {
+			return nil
+		}
+
+		if visiting[name] {
+			return fmt.Errorf("seed '%s' has a circular dependency", name)
+		}
+
+		visiting[name] = true
+		for _, dependency := range seed.dependsOn {
+			if err := visit(dependency, name); err != nil {
+				return err
+			}
+		}
+		visiting[name] = false
+
+		visited[name] = true
+		sorted = append(sorted, seed)
+		return nil
+	}
+
+	for _, name := range names {
+		if err := visit(name, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	return sorted, nil
+}
+
+func (db *DB) ensureSchemaSeedsTable() error {
+	_, err := db.Exec(`CREATE SCHEMA IF NOT EXISTS ?`, DBSafeQuery(db.config.SchemaSearchPath))
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(
+		`CREATE TABLE IF NOT EXISTS ?.? (name VARCHAR PRIMARY KEY, seeded_at TIMESTAMP NOT NULL DEFAULT NOW())`,
+		DBSafeQuery(db.config.SchemaSearchPath),
+		DBSafeQuery(db.config.SchemaSeedsTable),
+	)
+
+	return err
+}
+
 func (db *DB) ensureSchemaMigrationsTable() error {
 	count, err := db.
 		Model().
@@ -582,9 +899,108 @@ func (db *DB) ensureSchemaMigrationsTable() error {
 	return nil
 }
 
-func (db *DB) migratedVersions(tx *DBTx) ([]string, error) {
+// migratePath returns the migrations directory of the target database with the `migrate` asset layout.
+func (db *DB) migratePath(target string) string {
+	if db.asset == nil {
+		return dbMigratePath + target
+	}
+
+	return db.asset.Layout()["migrate"] + "/" + target
+}
+
+// loadSQLMigrations registers the `<version>_<name>.up.sql` and `<version>_<name>.down.sql` migrations for the target
+// database from the `migrate` asset layout which is read via the asset so that they work with the embedded filesystem
+// in release build. The SQL is executed in a transaction unless the file contains the `-- appy:no-transaction`
+// directive. A missing directory means there are no SQL migrations for the target database.
+func (db *DB) loadSQLMigrations(target string) error {
+	if db.asset == nil {
+		return nil
+	}
+
+	path := db.migratePath(target)
+	files, err := db.asset.ReadDir(path)
+	if err != nil {
+		if os.IsNotExist(err) || err == ErrNoEmbeddedAssets {
+			return nil
+		}
+
+		return err
+	}
+
+	migrations := map[string]*DBMigration{}
+	for _, file := range files {
+		var direction string
+		switch {
+		case strings.HasSuffix(file.Name(), ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(file.Name(), ".down.sql"):
+			direction = "down"
+		default:
+			continue
+		}
+
+		fn := path + "/" + file.Name()
+		version, err := migrationVersion(fn)
+		if err != nil {
+			return err
+		}
+
+		content, err := db.asset.ReadFile(fn)
+		if err != nil {
+			return err
+		}
+
+		m, ok := migrations[version]
+		if !ok {
+			m = &DBMigration{Version: version}
+			migrations[version] = m
+		}
+
+		query := string(content)
+		if direction == "up" {
+			m.File = fn
+			m.Up, m.UpTx = sqlMigration(query)
+			continue
+		}
+
+		m.Down, m.DownTx = sqlMigration(query)
+	}
+
+	versions := []string{}
+	for version := range migrations {
+		versions = append(versions, version)
+	}
+	sort.Strings(versions)
+
+	for _, version := range versions {
+		m := migrations[version]
+		if m.Up == nil && m.UpTx == nil {
+			return fmt.Errorf("migration version '%s' is missing the '.up.sql' file in '%s'", version, path)
+		}
+
+		if m.Down == nil && m.DownTx == nil {
+			err := fmt.Errorf("migration version '%s' is missing the '.down.sql' file in '%s'", version, path)
+			m.Down = func(*DB) error {
+				return err
+			}
+		}
+
+		if err := db.addMigration(m); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (db *DB) migratedVersions() ([]string, error) {
+	err := db.ensureSchemaMigrationsTable()
+	if err != nil {
+		return nil, err
+	}
+
 	var schemaMigrations []SchemaMigration
-	_, err := tx.Query(
+	_, err = db.Query(
 		&schemaMigrations,
 		`SELECT version FROM ?.? ORDER BY version ASC`,
 		DBSafeQuery(db.config.SchemaSearchPath),
@@ -602,6 +1018,123 @@ func (db *DB) migratedVersions(tx *DBTx) ([]string, error) {
 	return migratedVersions, nil
 }
 
+func (db *DB) migration(version string) *DBMigration {
+	for _, m := range db.migrations {
+		if m.Version == version {
+			return m
+		}
+	}
+
+	return nil
+}
+
+// migrateTo rolls back the migrated versions that are newer than the version and runs the pending migrations that
+// are older than or equal to the version. An empty version means migrating to the latest version.
+func (db *DB) migrateTo(version string) error {
+	if version != "" && db.migration(version) == nil {
+		return fmt.Errorf("migration version '%s' is not found", version)
+	}
+
+	migratedVersions, err := db.migratedVersions()
+	if err != nil {
+		return err
+	}
+
+	if version != "" {
+		var targets []string
+		for i := len(migratedVersions) - 1; i > -1 && migratedVersions[i] > version; i-- {
+			targets = append(targets, migratedVersions[i])
+		}
+
+		if err := db.migrateDownAll(targets); err != nil {
+			return err
+		}
+	}
+
+	applied := []string{}
+	for _, m := range db.migrations {
+		if version != "" && m.Version > version {
+			continue
+		}
+
+		if db.support.ArrayContains(migratedVersions, m.Version) {
+			continue
+		}
+
+		if err := db.migrateUp(m); err != nil {
+			return &DBMigrationError{Applied: applied, Direction: "up", Err: err, Version: m.Version}
+		}
+
+		applied = append(applied, m.Version)
+	}
+
+	return nil
+}
+
+// migrateDownAll rolls back the migrated versions in the given order.
+func (db *DB) migrateDownAll(versions []string) error {
+	applied := []string{}
+	for _, version := range versions {
+		m := db.migration(version)
+		if m == nil {
+			return &DBMigrationError{
+				Applied:   applied,
+				Direction: "down",
+				Err:       fmt.Errorf("migration version '%s' is not found", version),
+				Version:   version,
+			}
+		}
+
+		if err := db.migrateDown(m); err != nil {
+			return &DBMigrationError{Applied: applied, Direction: "down", Err: err, Version: version}
+		}
+
+		applied = append(applied, version)
+	}
+
+	return nil
+}
+
+// migrateDown runs the transactional migration and removes its version in its own transaction, or runs the
+// non-transactional migration outside of any transaction.
+func (db *DB) migrateDown(m *DBMigration) error {
+	if m.DownTx != nil {
+		return db.RunInTransaction(func(tx *DBTx) error {
+			if err := m.DownTx(tx); err != nil {
+				return err
+			}
+
+			return db.removeSchemaMigration(tx, m)
+		})
+	}
+
+	if err := m.Down(db); err != nil {
+		return err
+	}
+
+	return db.removeSchemaMigration(nil, m)
+}
+
+// migrateUp runs the transactional migration and records its version in its own transaction, or runs the
+// non-transactional migration outside of any transaction.
+func (db *DB) migrateUp(m *DBMigration) error {
+	if m.UpTx != nil {
+		return db.RunInTransaction(func(tx *DBTx) error {
+			if err := m.UpTx(tx); err != nil {
+				return err
+			}
+
+			return db.addSchemaMigration(tx, m)
+		})
+	}
+
+	if err := m.Up(db); err != nil {
+		return err
+	}
+
+	return db.addSchemaMigration(nil, m)
+}
+
 func (db *DB) registerMigration(up func(*DB) error, down func(*DB) error, upTx func(*DBTx) error, downTx func(*DBTx) error, args ...string) error {
 	file := migrationFile()
 
@@ -614,7 +1147,7 @@ func (db *DB) registerMigration(up func(*DB) error, down func(*DB) error, upTx f
 		return err
 	}
 
-	db.addMigration(&DBMigration{
+	return db.addMigration(&DBMigration{
 		File:    file,
 		Version: version,
 		Down:    down,
@@ -622,8 +1155,6 @@ func (db *DB) registerMigration(up func(*DB) error, down func(*DB) error, upTx f
 		Up:      up,
 		UpTx:    upTx,
 	})
-
-	return nil
 }
 
 func (db *DB) removeSchemaMigration(tx *DBTx, targetMigration *DBMigration) error {
@@ -649,6 +1180,105 @@ func (db *DB) removeSchemaMigration(tx *DBTx, targetMigration *DBMigration) erro
 	return err
 }
 
+func (db *DB) rollbackStep(step int) error {
+	if step < 1 {
+		return fmt.Errorf("rollback step must be greater than 0, got %d", step)
+	}
+
+	migratedVersions, err := db.migratedVersions()
+	if err != nil {
+		return err
+	}
+
+	var targets []string
+	for i := len(migratedVersions) - 1; i > -1 && len(targets) < step; i-- {
+		targets = append(targets, migratedVersions[i])
+	}
+
+	return db.migrateDownAll(targets)
+}
+
+// Error returns the migration error message.
+func (e *DBMigrationError) Error() string {
+	applied := "none"
+	if len(e.Applied) > 0 {
+		applied = strings.Join(e.Applied, ", ")
+	}
+
+	return fmt.Sprintf("migrating %s '%s' failed (applied: %s): %s", e.Direction, e.Version, applied, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *DBMigrationError) Unwrap() error {
+	return e.Err
+}
+
+// Tx returns the underlying go-pg's transaction, or nil for the savepoint within the test transaction.
+func (tx *DBTx) Tx() *pg.Tx {
+	return tx.tx
+}
+
+// Begin returns the current transaction. It does not start a new transaction.
+func (tx *DBTx) Begin() (*DBTx, error) {
+	return tx, nil
+}
+
+// Commit commits the transaction, or releases the savepoint within the test transaction.
+func (tx *DBTx) Commit() error {
+	if tx.tx == nil {
+		_, err := tx.Exec("RELEASE SAVEPOINT " + tx.savepoint)
+		return err
+	}
+
+	return tx.tx.Commit()
+}
+
+// Rollback aborts the transaction, or rolls back to the savepoint within the test transaction.
+func (tx *DBTx) Rollback() error {
+	if tx.tx == nil {
+		_, err := tx.Exec("ROLLBACK TO SAVEPOINT " + tx.savepoint)
+		return err
+	}
+
+	return tx.tx.Rollback()
+}
+
+// RunInTransaction runs the fn in the transaction which is rolled back if the fn returns an error or panics, otherwise
+// it is committed.
+func (tx *DBTx) RunInTransaction(fn func(*DBTx) error) error {
+	defer func() {
+		if err := recover(); err != nil {
+			_ = tx.Rollback()
+			panic(err)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// sqlMigration returns the migration that executes the query outside of a transaction if the query contains the
+// `-- appy:no-transaction` directive. Otherwise, it returns the migration that executes the query in a transaction.
+func sqlMigration(query string) (func(*DB) error, func(*DBTx) error) {
+	for _, line := range strings.Split(query, "\n") {
+		if strings.TrimSpace(line) == dbSQLMigrationNoTxDirective {
+			return func(db *DB) error {
+				_, err := db.Exec(query)
+				return err
+			}, nil
+		}
+	}
+
+	return nil, func(tx *DBTx) error {
+		_, err := tx.Exec(query)
+		return err
+	}
+}
+
 func migrationFile() string {
 	const depth = 32
 	var pcs [depth]uintptr
@@ -797,6 +1427,7 @@ func init() {
 			func(db *appy.DBTx) error {
 				return nil
 			},
+			appy.DBSeedName("default"),
 		)
 	}
 }
@@ -864,3 +1495,17 @@ func parseModulePath(mod []byte) string {
 
 	return ""
 }
+
+func (seed *dbSeed) runsIn(env string) bool {
+	if len(seed.envs) < 1 {
+		return true
+	}
+
+	for _, e := range seed.envs {
+		if e == env {
+			return true
+		}
+	}
+
+	return false
+}
@@ -3,12 +3,20 @@ package appy
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"errors"
+	"fmt"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/appist/appy/internal/sessionstore"
+	"github.com/go-pg/pg/v9"
 )
 
 type DBSuite struct {
@@ -44,7 +52,7 @@ func (s *DBSuite) TestDBGenerateMigration() {
 	s.Nil(errs)
 
 	migratePath := dbMigratePath + "primary"
-	db := NewDB(config["primary"], s.logger, s.support)
+	db := NewDB(config["primary"], nil, s.logger, s.support)
 	err := db.GenerateMigration("CreateUsers", "primary", false)
 	s.Nil(err)
 
@@ -80,6 +88,84 @@ func (s *DBSuite) TestDBGenerateMigration() {
 	s.Nil(err)
 }
 
+func (s *DBSuite) TestDBGenerateSQLMigration() {
+	oldDBMigratePath := dbMigratePath
+	dbMigratePath = "tmp/" + dbMigratePath
+	defer func() {
+		dbMigratePath = oldDBMigratePath
+	}()
+
+	migratePath := dbMigratePath + "primary"
+	db := NewDB(&DBConfig{}, nil, s.logger, s.support)
+
+	for _, tx := range []bool{false, true} {
+		err := db.GenerateSQLMigration("CreateUsers", "primary", tx)
+		s.Nil(err)
+
+		files, err := filepath.Glob(migratePath + "/*_create_users.*.sql")
+		s.Nil(err)
+		s.Equal(2, len(files))
+		s.True(strings.HasSuffix(files[0], "_create_users.down.sql"))
+		s.True(strings.HasSuffix(files[1], "_create_users.up.sql"))
+
+		for _, file := range files {
+			content, err := ioutil.ReadFile(file)
+			s.Nil(err)
+			s.Equal(!tx, strings.Contains(string(content), "-- appy:no-transaction"))
+		}
+
+		err = os.RemoveAll(dbMigratePath)
+		s.Nil(err)
+	}
+}
+
+func (s *DBSuite) TestDBSQLMigrations() {
+	asset := NewAsset(nil, nil, "testdata")
+	db := NewDB(&DBConfig{}, asset, s.logger, s.support)
+	db.RegisterMigrationTx(
+		func(h *DBTx) error { return nil },
+		func(h *DBTx) error { return nil },
+		"20200205165238_create_comments",
+	)
+	db.RegisterMigration(
+		func(h *DB) error { return nil },
+		func(h *DB) error { return nil },
+		"20200201165238_create_users",
+	)
+	s.Nil(db.loadSQLMigrations("primary"))
+
+	versions := []string{}
+	for _, m := range db.migrations {
+		versions = append(versions, m.Version)
+	}
+	s.Equal([]string{"20200201165238", "20200203165238", "20200204165238", "20200205165238", "20200206165238"}, versions)
+
+	posts := db.migration("20200203165238")
+	s.Equal("testdata/db/migrate/primary/20200203165238_create_posts.up.sql", posts.File)
+	s.NotNil(posts.UpTx)
+	s.NotNil(posts.DownTx)
+	s.Nil(posts.Up)
+	s.Nil(posts.Down)
+
+	index := db.migration("20200204165238")
+	s.NotNil(index.Up)
+	s.NotNil(index.Down)
+	s.Nil(index.UpTx)
+	s.Nil(index.DownTx)
+
+	tags := db.migration("20200206165238")
+	s.NotNil(tags.UpTx)
+	s.EqualError(tags.Down(db), "migration version '20200206165238' is missing the '.down.sql' file in 'testdata/db/migrate/primary'")
+
+	s.EqualError(db.loadSQLMigrations("primary"), "migration version '20200203165238' is already registered by 'testdata/db/migrate/primary/20200203165238_create_posts.up.sql'")
+	s.EqualError(db.loadSQLMigrations("invalid"), "migration version '20200203165238' is missing the '.up.sql' file in 'testdata/db/migrate/invalid'")
+	s.Nil(db.loadSQLMigrations("missing"))
+
+	db = NewDB(&DBConfig{}, nil, s.logger, s.support)
+	s.Nil(db.loadSQLMigrations("primary"))
+	s.Equal(0, len(db.migrations))
+}
+
 func (s *DBSuite) TestDBOps() {
 	os.Setenv("DB_ADDR_PRIMARY", "0.0.0.0:15432")
 	os.Setenv("DB_USER_PRIMARY", "postgres")
@@ -98,7 +184,7 @@ func (s *DBSuite) TestDBOps() {
 	config, errs := parseDBConfig(s.support)
 	s.Nil(errs)
 
-	db := NewDB(config["primary"], s.logger, s.support)
+	db := NewDB(config["primary"], nil, s.logger, s.support)
 
 	// Test DB create
 	targetDB := db.Config().Database
@@ -184,6 +270,25 @@ func (s *DBSuite) TestDBOps() {
 	s.Equal("up", migrations[0][0])
 	s.Equal("up", migrations[1][0])
 
+	// Test DB migration lock
+	conn := db.Conn()
+	_, err = conn.Exec(`SELECT pg_advisory_lock(?)`, db.migrationLockKey())
+	s.Nil(err)
+
+	db.config.MigrationLockTimeout = 200 * time.Millisecond
+	err = db.Migrate()
+	s.True(errors.Is(err, ErrDBMigrationLocked))
+
+	_, err = db.MigrateStatus()
+	s.True(errors.Is(err, ErrDBMigrationLocked))
+
+	_, err = conn.Exec(`SELECT pg_advisory_unlock(?)`, db.migrationLockKey())
+	s.Nil(err)
+	conn.Close()
+
+	err = db.Migrate()
+	s.Nil(err)
+
 	// Test DB seed
 	type User struct {
 		tableName struct{} `pg:",discard_unknown_columns"`
@@ -224,13 +329,271 @@ func (s *DBSuite) TestDBOps() {
 	s.Nil(err)
 	s.Equal(2, res.RowsReturned())
 
+	db.RegisterSeedTx(
+		func(h *DBTx) error {
+			_, err := h.Exec("UPDATE users SET updated_at = NOW()")
+			return err
+		},
+		DBSeedName("touch_users"),
+		DBSeedDependsOn("default"),
+	)
+	db.RegisterSeedTx(
+		func(h *DBTx) error {
+			return errors.New("should only run in production")
+		},
+		DBSeedName("demo_users"),
+		DBSeedEnvs("production"),
+	)
+
+	err = db.Seed("touch_users")
+	s.Nil(err)
+
+	err = db.Seed()
+	s.Nil(err)
+
+	res, err = db.Exec("SELECT * FROM users")
+	s.Nil(err)
+	s.Equal(2, res.RowsReturned())
+
+	res, err = db.Exec("SELECT name FROM schema_seeds")
+	s.Nil(err)
+	s.Equal(2, res.RowsReturned())
+
+	err = db.RunInTransaction(func(tx *DBTx) error {
+		s.NotNil(tx.Tx())
+		return nil
+	})
+	s.Nil(err)
+
+	// Test DB fixtures and factories in the test transaction
+	db.asset = NewAsset(nil, map[string]string{"fixtures": "testdata/db/fixtures"}, "")
+	userFactory := NewDBFactory(func(seq int) interface{} {
+		return &User{Email: fmt.Sprintf("user%d@appist.io", seq), Username: fmt.Sprintf("user%d", seq)}
+	})
+	err = db.RunInTestTx(func(tx *DBTx) error {
+		_, err := tx.Exec("DELETE FROM users")
+		s.Nil(err)
+		s.Nil(db.LoadFixtures(tx, "primary", "users"))
+
+		users, err := userFactory.CreateList(tx, 2, func(model interface{}) {
+			model.(*User).CreatedAt = time.Now()
+		})
+		s.Nil(err)
+		s.Equal(3, users[0].(*User).ID)
+
+		res, err := tx.Exec("SELECT * FROM users")
+		s.Nil(err)
+		s.Equal(4, res.RowsReturned())
+
+		return nil
+	})
+	s.Nil(err)
+
+	res, err = db.Exec("SELECT * FROM users")
+	s.Nil(err)
+	s.Equal(2, res.RowsReturned())
+
+	// Test DB manager's test isolation with the handler's transaction
+	dbManager := NewDBManager(nil, s.logger, s.support)
+	dbManager.databases["primary"].migrations = db.migrations
+	poolSize := dbManager.DB("primary").Config().PoolSize
+	s.Nil(dbManager.connectTest(context.Background()))
+	s.Equal(1, dbManager.DB("primary").Config().PoolSize)
+	s.Nil(dbManager.beginTest())
+
+	server := NewServer(NewAsset(nil, nil, ""), &Config{}, s.logger, s.support)
+	server.POST("/users", func(c *Context) {
+		err := dbManager.DB("primary").RunInTransaction(func(tx *DBTx) error {
+			s.Nil(tx.Tx())
+
+			_, err := tx.Exec("INSERT INTO users (email, username, created_at) VALUES ('test@appist.io', 'test', NOW())")
+			return err
+		})
+		s.Nil(err)
+
+		err = dbManager.DB("primary").RunInTransaction(func(tx *DBTx) error {
+			_, err := tx.Exec("INSERT INTO users (email, username, created_at) VALUES ('rollback@appist.io', 'rollback', NOW())")
+			s.Nil(err)
+
+			return errors.New("rollback")
+		})
+		s.EqualError(err, "rollback")
+	})
+	server.TestHTTPRequest("POST", "/users", nil, nil)
+
+	res, err = dbManager.DB("primary").Exec("SELECT * FROM users")
+	s.Nil(err)
+	s.Equal(3, res.RowsReturned())
+
+	// Test DB models in the test transaction
+	primary := dbManager.DB("primary")
+	_, err = primary.Exec(`CREATE TABLE model_users (id BIGSERIAL PRIMARY KEY, email VARCHAR, created_at TIMESTAMPTZ, updated_at TIMESTAMPTZ)`)
+	s.Nil(err)
+	_, err = primary.Exec(`CREATE TABLE model_posts (id BIGSERIAL PRIMARY KEY, title VARCHAR, created_at TIMESTAMPTZ, updated_at TIMESTAMPTZ, deleted_at TIMESTAMPTZ)`)
+	s.Nil(err)
+
+	modelUser1 := &modelUser{validateError: errors.New("email is missing")}
+	s.EqualError(primary.Save(modelUser1), "email is missing")
+	s.Equal([]string{"BeforeValidate", "Validate"}, modelUser1.calls)
+
+	modelUser1 = &modelUser{Email: "john"}
+	s.Equal(ValidationErrors{{Field: "Email", Tag: "email"}}, primary.Save(modelUser1))
+	s.Equal([]string{"BeforeValidate"}, modelUser1.calls)
+
+	modelUser1 = &modelUser{Email: "john@example.com"}
+	s.Nil(primary.CreateModel(modelUser1))
+	s.Equal([]string{"BeforeValidate", "Validate", "AfterValidate", "BeforeSave", "AfterSave"}, modelUser1.calls)
+	s.NotZero(modelUser1.ID)
+	s.False(modelUser1.CreatedAt.IsZero())
+
+	// The write is rolled back if the after save hook fails.
+	modelUser2 := &modelUser{Email: "mary@example.com", afterSaveError: errors.New("after save failed")}
+	s.EqualError(primary.CreateModel(modelUser2), "after save failed")
+	count, err := primary.Model(&modelUser{}).Where("email = ?", "mary@example.com").Count()
+	s.Nil(err)
+	s.Equal(0, count)
+
+	foundUser := &modelUser{}
+	s.Nil(primary.Find(foundUser, modelUser1.ID))
+	s.Equal("john@example.com", foundUser.Email)
+
+	foundUser.Email = "john@appist.io"
+	s.Nil(primary.UpdateModel(foundUser))
+	s.Equal([]string{"BeforeValidate", "Validate", "AfterValidate", "BeforeSave", "AfterSave"}, foundUser.calls)
+	s.Nil(primary.Find(modelUser1, foundUser.ID))
+	s.Equal("john@appist.io", modelUser1.Email)
+
+	destroyedUser := &modelUser{Model: Model{ID: foundUser.ID}}
+	s.Nil(primary.Destroy(destroyedUser))
+	s.Equal([]string{"BeforeDestroy", "AfterDestroy"}, destroyedUser.calls)
+	s.Equal(pg.ErrNoRows, primary.Find(&modelUser{}, foundUser.ID))
+
+	post := &modelPost{Title: "foo"}
+	s.Nil(primary.Save(post))
+	s.Nil(primary.Destroy(post))
+	s.Equal(pg.ErrNoRows, primary.Find(&modelPost{}, post.ID))
+
+	posts := []modelPost{}
+	s.Nil(primary.Where(&posts, "title = ?", "foo").Deleted().Select())
+	s.Equal(1, len(posts))
+	s.False(posts[0].DeletedAt.IsZero())
+
+	// Test postgres session store
+	sessionStore := sessionstore.NewPostgresStore(func() *pg.DB { return dbManager.DB("primary").DB }, "sessions", 0, []byte("481e5d98a31585148b8b1dfb6a3c0465"))
+	sessionStore.SetKeyPrefix("mysession:")
+
+	session, err := sessionStore.New(&http.Request{}, "_session")
+	s.Nil(err)
+	s.True(session.IsNew)
+	session.Values["id"] = 1
+	recorder := httptest.NewRecorder()
+	s.Nil(sessionStore.Save(&http.Request{}, recorder, session))
+
+	var key string
+	_, err = dbManager.DB("primary").QueryOne(pg.Scan(&key), "SELECT key FROM sessions")
+	s.Nil(err)
+	s.Equal("mysession:"+session.ID, key)
+
+	req := &http.Request{Header: http.Header{"Cookie": recorder.Header()["Set-Cookie"]}}
+	loaded, err := sessionStore.New(req, "_session")
+	s.Nil(err)
+	s.False(loaded.IsNew)
+	s.Equal(1, loaded.Values["id"])
+
+	_, err = dbManager.DB("primary").Exec("UPDATE sessions SET expires_at = NOW() - INTERVAL '1 second'")
+	s.Nil(err)
+	loaded, err = sessionStore.New(req, "_session")
+	s.Nil(err)
+	s.True(loaded.IsNew)
+
+	s.Nil(sessionStore.(*sessionstore.PostgresStore).DeleteExpired())
+	res, err = dbManager.DB("primary").Exec("SELECT * FROM sessions")
+	s.Nil(err)
+	s.Equal(0, res.RowsReturned())
+	s.Nil(sessionStore.Close())
+
+	s.Nil(dbManager.rollbackTest())
+	res, err = dbManager.DB("primary").Exec("SELECT * FROM users")
+	s.Nil(err)
+	s.Equal(2, res.RowsReturned())
+	s.Nil(dbManager.closeTest(context.Background()))
+	s.Equal(poolSize, dbManager.DB("primary").Config().PoolSize)
+
+	s.Nil(dbManager.connectTest(context.Background()))
+	s.Nil(dbManager.closeTest(context.Background()))
+	s.Equal(poolSize, dbManager.DB("primary").Config().PoolSize)
+
+	// The postgres session store's table is created again if it doesn't exist anymore, e.g. its creation is rolled
+	// back with the test transaction.
+	sessionStore = sessionstore.NewPostgresStore(func() *pg.DB { return db.DB }, "sessions", 0, []byte("481e5d98a31585148b8b1dfb6a3c0465"))
+	s.Nil(sessionStore.Save(&http.Request{}, httptest.NewRecorder(), session))
+
+	_, err = db.Exec("DROP TABLE sessions")
+	s.Nil(err)
+	err = sessionStore.Save(&http.Request{}, httptest.NewRecorder(), session)
+	s.Contains(err.Error(), `relation "sessions" does not exist`)
+	s.Nil(sessionStore.Save(&http.Request{}, httptest.NewRecorder(), session))
+
+	_, err = db.Exec("DROP TABLE sessions")
+	s.Nil(err)
+	s.Nil(sessionStore.Close())
+
 	// Test DB dump schema
-	err = db.DumpSchema("appy")
+	err = db.DumpSchemaWithFormat("appy", "native")
 	s.Nil(err)
 
 	schemaPath := dbMigratePath + "/appy/schema.go"
-	_, err = os.Stat(schemaPath)
-	s.Equal(false, os.IsNotExist(err))
+	content, err := ioutil.ReadFile(schemaPath)
+	s.Nil(err)
+	s.Contains(string(content), "CREATE TABLE IF NOT EXISTS public.users (\n    id integer DEFAULT nextval('users_id_seq'::regclass) NOT NULL,")
+	s.Contains(string(content), "ALTER TABLE ONLY public.users\n    ADD CONSTRAINT users_pkey PRIMARY KEY (id);")
+	s.Contains(string(content), "CREATE INDEX users_on_deleted_at ON public.users USING btree (deleted_at);")
+	s.Contains(string(content), "INSERT INTO public.schema_migrations (version) VALUES")
+
+	err = db.DumpSchemaWithFormat("appy", "native")
+	s.Nil(err)
+
+	dumped, err := ioutil.ReadFile(schemaPath)
+	s.Nil(err)
+	s.Equal(string(content), string(dumped))
+
+	// Test DB schema check
+	sql, err := db.dumpSchemaNative()
+	s.Nil(err)
+	db.SetSchema(sql)
+
+	diff, err := db.CheckSchema()
+	s.Nil(err)
+	s.True(diff.IsEmpty())
+
+	_, err = db.Exec(`ALTER TABLE users ADD COLUMN nickname VARCHAR;`)
+	s.Nil(err)
+
+	diff, err = db.CheckSchema()
+	s.Nil(err)
+	s.Equal([]string{"extra column 'users.nickname'"}, diff.Report())
+
+	_, err = db.Exec(`ALTER TABLE users DROP COLUMN nickname;`)
+	s.Nil(err)
+
+	_, err = db.Exec(`CREATE TABLE sessions (key TEXT PRIMARY KEY, expires_at TIMESTAMPTZ NOT NULL);
+CREATE INDEX sessions_expires_at_idx ON sessions (expires_at);`)
+	s.Nil(err)
+
+	diff, err = db.CheckSchema()
+	s.Nil(err)
+	s.Equal([]string{"extra table 'sessions'", "extra index 'sessions_expires_at_idx'"}, diff.Report())
+
+	diff, err = db.CheckSchema("sessions")
+	s.Nil(err)
+	s.True(diff.IsEmpty())
+
+	_, err = db.Exec(`DROP TABLE sessions;`)
+	s.Nil(err)
+
+	err = db.DumpSchemaWithFormat("appy", "xml")
+	s.EqualError(err, "schema dump format 'xml' is not supported")
+
 	err = os.RemoveAll(dbMigratePath)
 	s.Nil(err)
 
@@ -253,6 +616,126 @@ func (s *DBSuite) TestDBOps() {
 	s.Equal("down", migrations[0][0])
 	s.Equal("down", migrations[1][0])
 
+	// Test DB migrate to version
+	err = db.MigrateTo("20200201165238")
+	s.Nil(err)
+
+	migrations, err = db.MigrateStatus()
+	s.Nil(err)
+	s.Equal("up", migrations[0][0])
+	s.Equal("down", migrations[1][0])
+
+	err = db.MigrateTo("20200202165238")
+	s.Nil(err)
+
+	migrations, err = db.MigrateStatus()
+	s.Nil(err)
+	s.Equal("up", migrations[0][0])
+	s.Equal("up", migrations[1][0])
+
+	err = db.MigrateTo("20200201165238")
+	s.Nil(err)
+
+	migrations, err = db.MigrateStatus()
+	s.Nil(err)
+	s.Equal("up", migrations[0][0])
+	s.Equal("down", migrations[1][0])
+
+	err = db.MigrateTo("20200101000000")
+	s.EqualError(err, "migration version '20200101000000' is not found")
+
+	// Test DB migrate redo
+	err = db.Migrate()
+	s.Nil(err)
+
+	err = db.MigrateRedo(2)
+	s.Nil(err)
+
+	migrations, err = db.MigrateStatus()
+	s.Nil(err)
+	s.Equal("up", migrations[0][0])
+	s.Equal("up", migrations[1][0])
+
+	// Test DB rollback with step
+	err = db.RollbackStep(0)
+	s.EqualError(err, "rollback step must be greater than 0, got 0")
+
+	err = db.RollbackStep(2)
+	s.Nil(err)
+
+	migrations, err = db.MigrateStatus()
+	s.Nil(err)
+	s.Equal("down", migrations[0][0])
+	s.Equal("down", migrations[1][0])
+
+	// Test DB migrate with mixed tx/non-tx migrations that fails halfway
+	db.RegisterMigrationTx(
+		func(h *DBTx) error {
+			_, err := h.Exec(`CREATE TABLE posts (id SERIAL PRIMARY KEY, title VARCHAR);`)
+			return err
+		},
+		func(h *DBTx) error {
+			_, err := h.Exec(`DROP TABLE IF EXISTS posts;`)
+			return err
+		},
+		"20200203165238_create_posts",
+	)
+
+	db.RegisterMigration(
+		func(h *DB) error {
+			_, err := h.Exec(`CREATE INDEX CONCURRENTLY posts_on_title ON posts (title);`)
+			return err
+		},
+		func(h *DB) error {
+			_, err := h.Exec(`DROP INDEX posts_on_title;`)
+			return err
+		},
+		"20200204165238_add_posts_on_title_index",
+	)
+
+	db.RegisterMigrationTx(
+		func(h *DBTx) error {
+			_, err := h.Exec(`CREATE TABLE comments (id SERIAL PRIMARY KEY);`)
+			if err != nil {
+				return err
+			}
+
+			_, err = h.Exec(`ALTER TABLE comments ADD COLUMN post_id INT4 REFERENCES missing (id);`)
+			return err
+		},
+		func(h *DBTx) error {
+			_, err := h.Exec(`DROP TABLE IF EXISTS comments;`)
+			return err
+		},
+		"20200205165238_create_comments",
+	)
+
+	err = db.Migrate()
+	migrationErr := &DBMigrationError{}
+	s.True(errors.As(err, &migrationErr))
+	s.Equal("up", migrationErr.Direction)
+	s.Equal("20200205165238", migrationErr.Version)
+	s.Equal([]string{"20200201165238", "20200202165238", "20200203165238", "20200204165238"}, migrationErr.Applied)
+
+	migrations, err = db.MigrateStatus()
+	s.Nil(err)
+	s.Equal(5, len(migrations))
+	s.Equal("up", migrations[3][0])
+	s.Equal("down", migrations[4][0])
+
+	res, err = db.Exec("SELECT tablename FROM pg_tables WHERE tablename = ?;", "comments")
+	s.Nil(err)
+	s.Equal(0, res.RowsReturned())
+
+	err = db.RollbackStep(4)
+	s.Nil(err)
+
+	migrations, err = db.MigrateStatus()
+	s.Nil(err)
+	for _, migration := range migrations {
+		s.Equal("down", migration[0])
+	}
+
 	// Test DB drop
 	targetDB = db.Config().Database
 	db.config.Database = "postgres"
@@ -279,6 +762,244 @@ func (s *DBSuite) TestDBOps() {
 	s.NotNil(errs)
 }
 
+func (s *DBSuite) TestDBMigrationLock() {
+	db := NewDB(&DBConfig{SchemaSearchPath: "public", SchemaMigrationsTable: "schema_migrations"}, nil, s.logger, s.support)
+	s.Equal(ErrDBNotConnected, db.Migrate())
+	s.Equal(ErrDBNotConnected, db.Rollback())
+
+	_, err := db.MigrateStatus()
+	s.Equal(ErrDBNotConnected, err)
+
+	db.config.Database = "appy"
+	key := db.migrationLockKey()
+	s.Equal(key, db.migrationLockKey())
+
+	db.config.SchemaMigrationsTable = "custom_migrations"
+	s.NotEqual(key, db.migrationLockKey())
+
+	db.config.SchemaMigrationsTable = "schema_migrations"
+	db.config.Database = "appy_test"
+	s.NotEqual(key, db.migrationLockKey())
+}
+
+func (s *DBSuite) TestDBRunInTestTxWithoutConnection() {
+	db := NewDB(&DBConfig{}, nil, s.logger, s.support)
+	s.Equal(ErrDBNotConnected, db.RunInTestTx(func(tx *DBTx) error {
+		return nil
+	}))
+}
+
+func (s *DBSuite) TestDBRunInTransactionWithoutConnection() {
+	db := NewDB(&DBConfig{}, nil, s.logger, s.support)
+	s.Equal(ErrDBNotConnected, db.RunInTransaction(func(tx *DBTx) error {
+		return nil
+	}))
+
+	_, err := db.Begin()
+	s.Equal(ErrDBNotConnected, err)
+}
+
+func (s *DBSuite) TestDBMigrationError() {
+	err := &DBMigrationError{
+		Applied:   []string{"20200201165238", "20200202165238"},
+		Direction: "up",
+		Err:       ErrDBNotConnected,
+		Version:   "20200203165238",
+	}
+
+	s.EqualError(err, "migrating up '20200203165238' failed (applied: 20200201165238, 20200202165238): database is not connected")
+	s.True(errors.Is(err, ErrDBNotConnected))
+
+	err = &DBMigrationError{Direction: "down", Err: ErrDBNotConnected, Version: "20200203165238"}
+	s.EqualError(err, "migrating down '20200203165238' failed (applied: none): database is not connected")
+}
+
+func (s *DBSuite) TestDBSchemaSQL() {
+	schema := &dbSchema{
+		Name: "public",
+		Constraints: []dbSchemaConstraint{
+			{Table: "posts", Name: "posts_pkey", Type: "p", Definition: "PRIMARY KEY (id)"},
+			{Table: "posts", Name: "posts_user_id_fkey", Type: "f", Definition: "FOREIGN KEY (user_id) REFERENCES public.users(id)"},
+			{Table: "users", Name: "users_pkey", Type: "p", Definition: "PRIMARY KEY (id)"},
+		},
+		Extensions: []dbSchemaExtension{{Name: "pgcrypto", Schema: "public"}},
+		Functions: []dbSchemaFunction{
+			{Name: "touch()", Definition: "CREATE OR REPLACE FUNCTION public.touch()\n RETURNS trigger\n LANGUAGE plpgsql\nAS $function$BEGIN NEW.updated_at = NOW(); RETURN NEW; END$function$\n"},
+		},
+		Indexes: []dbSchemaIndex{{Name: "posts_on_title", Definition: "CREATE INDEX posts_on_title ON public.posts USING btree (title)"}},
+		Sequences: []dbSchemaSequence{
+			{Name: "posts_id_seq", DataType: "integer", Start: 1, Increment: 1, Min: 1, Max: 2147483647, OwnedTable: "posts", OwnedColumn: "id"},
+		},
+		Tables: []dbSchemaTable{
+			{
+				Name: "posts",
+				Columns: []dbSchemaColumn{
+					{Name: "id", DataType: "integer", Default: "nextval('public.posts_id_seq'::regclass)", NotNull: true},
+					{Name: "title", DataType: "character varying(255)"},
+					{Name: "user_id", DataType: "bigint"},
+				},
+			},
+			{
+				Name: "users",
+				Columns: []dbSchemaColumn{
+					{Name: "id", DataType: "bigint", Identity: "d", NotNull: true},
+				},
+			},
+		},
+		Triggers: []dbSchemaTrigger{
+			{Name: "posts_touch", Definition: "CREATE TRIGGER posts_touch BEFORE UPDATE ON public.posts FOR EACH ROW EXECUTE FUNCTION touch()"},
+		},
+		Types: []dbSchemaType{
+			{Name: "mood", Type: "e", Definition: "'happy',\n    'sad'"},
+			{Name: "address", Type: "c", Definition: "city text,\n    zip integer"},
+			{Name: "email", Type: "d", Definition: "text NOT NULL CONSTRAINT email_check CHECK ((VALUE ~~ '%@%'::text))"},
+		},
+		Views: []dbSchemaView{
+			{Name: "a_recent_post_titles", Definition: " SELECT post_titles.title\n   FROM post_titles;", DependsOn: []string{"post_titles"}},
+			{Name: "post_titles", Definition: " SELECT posts.title\n   FROM posts;"},
+			{Name: "user_ids", Definition: " SELECT users.id\n   FROM users;", Materialized: true},
+		},
+	}
+
+	s.Equal(`CREATE EXTENSION IF NOT EXISTS pgcrypto WITH SCHEMA public;
+
+CREATE SCHEMA IF NOT EXISTS public;
+
+CREATE TYPE public.mood AS ENUM (
+    'happy',
+    'sad'
+);
+
+CREATE TYPE public.address AS (
+    city text,
+    zip integer
+);
+
+CREATE DOMAIN public.email AS text NOT NULL CONSTRAINT email_check CHECK ((VALUE ~~ '%@%'::text));
+
+SET check_function_bodies = false;
+
+CREATE OR REPLACE FUNCTION public.touch()
+ RETURNS trigger
+ LANGUAGE plpgsql
+AS $function$BEGIN NEW.updated_at = NOW(); RETURN NEW; END$function$;
+
+CREATE SEQUENCE IF NOT EXISTS public.posts_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    MINVALUE 1
+    MAXVALUE 2147483647
+    NO CYCLE;
+
+CREATE TABLE IF NOT EXISTS public.posts (
+    id integer DEFAULT nextval('public.posts_id_seq'::regclass) NOT NULL,
+    title character varying(255),
+    user_id bigint
+);
+
+CREATE TABLE IF NOT EXISTS public.users (
+    id bigint GENERATED BY DEFAULT AS IDENTITY NOT NULL
+);
+
+ALTER SEQUENCE public.posts_id_seq OWNED BY public.posts.id;
+
+ALTER TABLE ONLY public.posts
+    ADD CONSTRAINT posts_pkey PRIMARY KEY (id);
+
+ALTER TABLE ONLY public.users
+    ADD CONSTRAINT users_pkey PRIMARY KEY (id);
+
+ALTER TABLE ONLY public.posts
+    ADD CONSTRAINT posts_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id);
+
+CREATE INDEX posts_on_title ON public.posts USING btree (title);
+
+CREATE OR REPLACE VIEW public.post_titles AS
+SELECT posts.title
+   FROM posts;
+
+CREATE OR REPLACE VIEW public.a_recent_post_titles AS
+SELECT post_titles.title
+   FROM post_titles;
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS public.user_ids AS
+SELECT users.id
+   FROM users
+WITH NO DATA;
+
+CREATE TRIGGER posts_touch BEFORE UPDATE ON public.posts FOR EACH ROW EXECUTE FUNCTION touch();`, schema.SQL())
+}
+
+func (s *DBSuite) TestDBSchemaCheck() {
+	db := NewDB(&DBConfig{Options: pg.Options{Database: "appy"}}, nil, s.logger, s.support)
+	_, err := db.CheckSchema()
+	s.EqualError(err, "schema for 'appy' database is not dumped yet")
+
+	sql := `CREATE SCHEMA IF NOT EXISTS public;
+
+CREATE TABLE IF NOT EXISTS public.posts (
+    id integer DEFAULT nextval('public.posts_id_seq'::regclass) NOT NULL,
+    title character varying(255),
+    CONSTRAINT title_length CHECK ((char_length((title)::text) > 0))
+);
+
+CREATE TABLE IF NOT EXISTS public."user" (
+    id bigint NOT NULL,
+    email character varying NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS public.tags (
+    id bigint NOT NULL
+) PARTITION BY RANGE (id);
+
+CREATE UNIQUE INDEX user_on_email ON public."user" USING btree (email);
+
+CREATE INDEX posts_on_title ON public.posts USING btree (title);
+
+INSERT INTO public.schema_migrations (version) VALUES
+('20200201165238'),
+('20200202165238');`
+
+	dumped, versions := parseDBSchema(sql)
+	s.Equal([]string{"posts", `"user"`, "tags"}, dumped.tableNames())
+	s.Equal([]string{"posts.id", "posts.title", `"user".id`, `"user".email`, "tags.id"}, dumped.columnNames())
+	s.Equal([]string{"user_on_email", "posts_on_title"}, dumped.indexNames())
+	s.Equal([]string{"20200201165238", "20200202165238"}, versions)
+
+	live := &dbSchema{
+		Tables: []dbSchemaTable{
+			{Name: "posts", Columns: []dbSchemaColumn{{Name: "id"}, {Name: "body"}}},
+			{Name: `"user"`, Columns: []dbSchemaColumn{{Name: "id"}, {Name: "email"}}},
+			{Name: "comments", Columns: []dbSchemaColumn{{Name: "id"}}},
+		},
+		Indexes: []dbSchemaIndex{{Name: "posts_on_title"}, {Name: "posts_on_body"}},
+	}
+
+	diff := diffDBSchema(dumped, live, append(versions, "20200203165238"), []string{"20200201165238", "20200101000000"})
+	s.False(diff.IsEmpty())
+	s.Equal([]string{
+		"missing table 'tags'",
+		"extra table 'comments'",
+		"missing column 'posts.title'",
+		"extra column 'posts.body'",
+		"missing index 'user_on_email'",
+		"extra index 'posts_on_body'",
+		"unapplied version '20200202165238'",
+		"unapplied version '20200203165238'",
+		"unknown version '20200101000000'",
+	}, diff.Report())
+
+	diff = diffDBSchema(dumped, dumped, versions, versions)
+	s.True(diff.IsEmpty())
+
+	withoutPosts := dumped.withoutTables([]string{"posts"})
+	s.Equal([]string{`"user"`, "tags"}, withoutPosts.tableNames())
+	s.Equal([]string{`"user".id`, `"user".email`, "tags.id"}, withoutPosts.columnNames())
+	s.Equal([]string{"user_on_email"}, withoutPosts.indexNames())
+	s.Equal(dumped, dumped.withoutTables(nil))
+}
+
 func (s *DBSuite) TestDBSchema() {
 	os.Setenv("DB_ADDR_PRIMARY", "0.0.0.0:15432")
 	os.Setenv("DB_USER_PRIMARY", "postgres")
@@ -294,7 +1015,7 @@ func (s *DBSuite) TestDBSchema() {
 	config, errs := parseDBConfig(s.support)
 	s.Nil(errs)
 
-	db := NewDB(config["primary"], s.logger, s.support)
+	db := NewDB(config["primary"], nil, s.logger, s.support)
 	s.Equal("", db.Schema())
 
 	db.SetSchema("SQL schema")
@@ -305,6 +1026,51 @@ func (s *DBSuite) TestDBSeedTpl() {
 	tpl, err := seedTpl("primary")
 	s.Nil(err)
 	s.Contains(string(tpl), "db.RegisterSeedTx(")
+	s.Contains(string(tpl), `appy.DBSeedName("default")`)
+}
+
+func (s *DBSuite) TestDBSeeds() {
+	db := NewDB(&DBConfig{}, nil, s.logger, s.support)
+	s.Equal(ErrDBNotConnected, db.Seed())
+
+	seed := func(tx *DBTx) error { return nil }
+	s.Nil(db.addSeed(seed))
+	s.EqualError(db.addSeed(seed), "seed 'default' is already registered")
+	s.Nil(db.addSeed(seed, DBSeedName("posts"), DBSeedDependsOn("users", "tags")))
+	s.Nil(db.addSeed(seed, DBSeedName("users"), DBSeedEnvs("development", "test")))
+	s.Nil(db.addSeed(seed, DBSeedName("tags")))
+	s.Equal([]string{"default", "posts", "users", "tags"}, db.seedNames())
+
+	sortedNames := func(names ...string) []string {
+		seeds, err := db.sortSeeds(names...)
+		s.Nil(err)
+
+		sorted := []string{}
+		for _, seed := range seeds {
+			sorted = append(sorted, seed.name)
+		}
+
+		return sorted
+	}
+	s.Equal([]string{"default", "users", "tags", "posts"}, sortedNames())
+	s.Equal([]string{"users", "tags", "posts"}, sortedNames("posts"))
+	s.Equal([]string{"tags", "users"}, sortedNames("tags", "users"))
+
+	_, err := db.sortSeeds("comments")
+	s.EqualError(err, "seed 'comments' is not found")
+
+	s.Nil(db.addSeed(seed, DBSeedName("comments"), DBSeedDependsOn("likes")))
+	_, err = db.sortSeeds("comments")
+	s.EqualError(err, "seed 'comments' depends on 'likes' which is not found")
+
+	s.Nil(db.addSeed(seed, DBSeedName("a"), DBSeedDependsOn("b")))
+	s.Nil(db.addSeed(seed, DBSeedName("b"), DBSeedDependsOn("a")))
+	_, err = db.sortSeeds("a")
+	s.EqualError(err, "seed 'a' has a circular dependency")
+
+	s.True((&dbSeed{}).runsIn("production"))
+	s.True((&dbSeed{envs: []string{"development", "test"}}).runsIn("test"))
+	s.False((&dbSeed{envs: []string{"development", "test"}}).runsIn("production"))
 }
 
 func TestDBSuite(t *testing.T) {
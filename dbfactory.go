@@ -0,0 +1,109 @@
+package appy
+
+import "sync"
+
+// DBFactory builds the models for testing with a sequence number that is unique per factory, and creates them with
+// their associations, e.g.
+//
+//	userFactory := appy.NewDBFactory(func(seq int) interface{} {
+//	  return &User{Email: fmt.Sprintf("user%d@appist.io", seq)}
+//	})
+//
+//	postFactory := appy.NewDBFactory(func(seq int) interface{} {
+//	  return &Post{Title: fmt.Sprintf("Post %d", seq)}
+//	}).Associate(func(tx *appy.DBTx, model interface{}) error {
+//	  user, err := userFactory.Create(tx)
+//	  if err != nil {
+//	    return err
+//	  }
+//
+//	  model.(*Post).UserID = user.(*User).ID
+//	  return nil
+//	})
+type DBFactory struct {
+	associations []func(tx *DBTx, model interface{}) error
+	build        func(seq int) interface{}
+	mu           *sync.Mutex
+	seq          int
+}
+
+// NewDBFactory initializes the factory with the build func that returns a pointer to the model for the sequence number
+// which starts from 1.
+func NewDBFactory(build func(seq int) interface{}) *DBFactory {
+	return &DBFactory{
+		associations: []func(tx *DBTx, model interface{}) error{},
+		build:        build,
+		mu:           &sync.Mutex{},
+	}
+}
+
+// Associate registers the func that creates the associated models and assigns them to the model in the same
+// transaction before the model is created.
+func (f *DBFactory) Associate(fn func(tx *DBTx, model interface{}) error) *DBFactory {
+	f.associations = append(f.associations, fn)
+
+	return f
+}
+
+// Build returns the model with the next sequence number and the overrides applied, without creating it or its
+// associations.
+func (f *DBFactory) Build(overrides ...func(model interface{})) interface{} {
+	model := f.next()
+	for _, override := range overrides {
+		override(model)
+	}
+
+	return model
+}
+
+// Create builds the model, creates its associations, applies the overrides and inserts the model in the transaction.
+func (f *DBFactory) Create(tx *DBTx, overrides ...func(model interface{})) (interface{}, error) {
+	model := f.next()
+	for _, association := range f.associations {
+		if err := association(tx, model); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, override := range overrides {
+		override(model)
+	}
+
+	if _, err := tx.Model(model).Insert(); err != nil {
+		return nil, err
+	}
+
+	return model, nil
+}
+
+// CreateList creates the count number of models in the transaction.
+func (f *DBFactory) CreateList(tx *DBTx, count int, overrides ...func(model interface{})) ([]interface{}, error) {
+	models := []interface{}{}
+	for i := 0; i < count; i++ {
+		model, err := f.Create(tx, overrides...)
+		if err != nil {
+			return nil, err
+		}
+
+		models = append(models, model)
+	}
+
+	return models, nil
+}
+
+// Reset resets the sequence number.
+func (f *DBFactory) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.seq = 0
+}
+
+func (f *DBFactory) next() interface{} {
+	f.mu.Lock()
+	f.seq++
+	seq := f.seq
+	f.mu.Unlock()
+
+	return f.build(seq)
+}
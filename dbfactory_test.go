@@ -0,0 +1,91 @@
+package appy
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-pg/pg/v9"
+)
+
+type DBFactorySuite struct {
+	TestSuite
+	db *DB
+}
+
+type dbFactoryUser struct {
+	Model
+	Email string
+	Admin bool
+}
+
+func (s *DBFactorySuite) SetupTest() {
+	logger, _, _ := NewFakeLogger()
+	s.db = NewDB(&DBConfig{
+		Options: pg.Options{Addr: "0.0.0.0:15432", User: "postgres", Password: "whatever", Database: "postgres"},
+	}, nil, logger, &Support{})
+}
+
+func (s *DBFactorySuite) TestBuild() {
+	factory := NewDBFactory(func(seq int) interface{} {
+		return &dbFactoryUser{Email: fmt.Sprintf("user%d@appist.io", seq)}
+	})
+
+	s.Equal(&dbFactoryUser{Email: "user1@appist.io"}, factory.Build())
+	s.Equal(&dbFactoryUser{Email: "user2@appist.io", Admin: true}, factory.Build(func(model interface{}) {
+		model.(*dbFactoryUser).Admin = true
+	}))
+
+	factory.Reset()
+	s.Equal(&dbFactoryUser{Email: "user1@appist.io"}, factory.Build())
+}
+
+func (s *DBFactorySuite) TestCreate() {
+	s.Nil(s.db.Connect())
+	defer s.db.Close()
+
+	err := s.db.RunInTransaction(func(tx *DBTx) error {
+		_, err := tx.Exec(`CREATE TEMPORARY TABLE db_factory_users (id BIGSERIAL PRIMARY KEY, email VARCHAR, admin BOOLEAN, created_at TIMESTAMPTZ, updated_at TIMESTAMPTZ)`)
+		s.Nil(err)
+
+		calls := []string{}
+		failingFactory := NewDBFactory(func(seq int) interface{} {
+			return &dbFactoryUser{Email: fmt.Sprintf("user%d@appist.io", seq)}
+		}).Associate(func(tx *DBTx, model interface{}) error {
+			calls = append(calls, model.(*dbFactoryUser).Email)
+			return errors.New("association failed")
+		})
+		_, err = failingFactory.Create(tx)
+		s.EqualError(err, "association failed")
+		s.Equal([]string{"user1@appist.io"}, calls)
+
+		factory := NewDBFactory(func(seq int) interface{} {
+			return &dbFactoryUser{Email: fmt.Sprintf("user%d@appist.io", seq)}
+		}).Associate(func(tx *DBTx, model interface{}) error {
+			model.(*dbFactoryUser).Admin = true
+			return nil
+		})
+		user, err := factory.Create(tx, func(model interface{}) {
+			model.(*dbFactoryUser).Email = "override@appist.io"
+		})
+		s.Nil(err)
+		s.NotZero(user.(*dbFactoryUser).ID)
+
+		created := &dbFactoryUser{}
+		s.Nil(tx.Model(created).Where("id = ?", user.(*dbFactoryUser).ID).Select())
+		s.Equal("override@appist.io", created.Email)
+		s.True(created.Admin)
+
+		users, err := factory.CreateList(tx, 2)
+		s.Nil(err)
+		s.Equal("user2@appist.io", users[0].(*dbFactoryUser).Email)
+		s.Equal("user3@appist.io", users[1].(*dbFactoryUser).Email)
+
+		return errors.New("rollback")
+	})
+	s.EqualError(err, "rollback")
+}
+
+func TestDBFactorySuite(t *testing.T) {
+	RunTestSuite(t, new(DBFactorySuite))
+}
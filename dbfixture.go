@@ -0,0 +1,150 @@
+package appy
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-pg/pg/v9"
+	"github.com/go-pg/pg/v9/orm"
+	"gopkg.in/yaml.v2"
+)
+
+type dbFixture struct {
+	table string
+	rows  []map[string]interface{}
+}
+
+// LoadFixtures inserts the YAML fixtures of the database in the transaction. The fixtures are read from
+// `<fixtures>/<database>/<table>.yml` with the `fixtures` asset layout, each of which contains the rows keyed by
+// their labels:
+//
+//	john:
+//	  id: 1
+//	  email: john@appist.io
+//
+// The tables are loaded in the given order, or all the tables sorted by name if none is given. The serial `id`
+// sequence is advanced past the fixtures so that the rows inserted later don't conflict with them.
+func (db *DB) LoadFixtures(tx *DBTx, database string, tables ...string) error {
+	fixtures, err := db.readFixtures(database, tables...)
+	if err != nil {
+		return err
+	}
+
+	return insertFixtures(tx, fixtures)
+}
+
+func (db *DB) readFixtures(database string, tables ...string) ([]dbFixture, error) {
+	if db.asset == nil {
+		return nil, fmt.Errorf("asset is missing to load '%s' database fixtures", database)
+	}
+
+	path := db.asset.Layout()["fixtures"] + "/" + database
+	files, err := db.asset.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	filenames := map[string]string{}
+	for _, file := range files {
+		ext := filepath.Ext(file.Name())
+		if file.IsDir() || (ext != ".yml" && ext != ".yaml") {
+			continue
+		}
+
+		filenames[strings.TrimSuffix(file.Name(), ext)] = path + "/" + file.Name()
+	}
+
+	if len(tables) < 1 {
+		for table := range filenames {
+			tables = append(tables, table)
+		}
+
+		sort.Strings(tables)
+	}
+
+	fixtures := []dbFixture{}
+	for _, table := range tables {
+		filename, ok := filenames[table]
+		if !ok {
+			return nil, fmt.Errorf("fixtures for '%s' table in '%s' database are not found", table, database)
+		}
+
+		content, err := db.asset.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+
+		rows := map[string]map[string]interface{}{}
+		if err := yaml.Unmarshal(content, &rows); err != nil {
+			return nil, fmt.Errorf("unable to parse '%s': %s", filename, err)
+		}
+
+		labels := []string{}
+		for label := range rows {
+			labels = append(labels, label)
+		}
+		sort.Strings(labels)
+
+		fixture := dbFixture{table: table}
+		for _, label := range labels {
+			fixture.rows = append(fixture.rows, rows[label])
+		}
+
+		fixtures = append(fixtures, fixture)
+	}
+
+	return fixtures, nil
+}
+
+func insertFixtures(db orm.DB, fixtures []dbFixture) error {
+	for _, fixture := range fixtures {
+		hasID := false
+
+		for _, row := range fixture.rows {
+			columns := []string{}
+			for column := range row {
+				columns = append(columns, column)
+			}
+			sort.Strings(columns)
+
+			idents := []interface{}{}
+			values := []interface{}{}
+			for _, column := range columns {
+				idents = append(idents, pg.Ident(column))
+				values = append(values, row[column])
+
+				if column == "id" {
+					hasID = true
+				}
+			}
+
+			if _, err := db.Exec("INSERT INTO ? (?) VALUES (?)", pg.Ident(fixture.table), pg.In(idents),
+				pg.In(values)); err != nil {
+				return fmt.Errorf("unable to insert fixtures into '%s' table: %s", fixture.table, err)
+			}
+		}
+
+		if !hasID {
+			continue
+		}
+
+		// The `id` column might not be backed by a sequence, e.g. UUID.
+		var sequence string
+		if _, err := db.QueryOne(pg.Scan(&sequence), "SELECT pg_get_serial_sequence(?, 'id')", fixture.table); err != nil {
+			return err
+		}
+
+		if sequence == "" {
+			continue
+		}
+
+		if _, err := db.Exec("SELECT setval(?, COALESCE(MAX(id), 1), MAX(id) IS NOT NULL) FROM ?", sequence,
+			pg.Ident(fixture.table)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package appy
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/go-pg/pg/v9"
+)
+
+type DBFixtureSuite struct {
+	TestSuite
+	db *DB
+}
+
+func (s *DBFixtureSuite) SetupTest() {
+	logger, _, _ := NewFakeLogger()
+	asset := NewAsset(http.Dir("testdata"), map[string]string{
+		"fixtures": "testdata/db/fixtures",
+	}, "")
+	s.db = NewDB(&DBConfig{
+		Options: pg.Options{Addr: "0.0.0.0:15432", User: "postgres", Password: "whatever", Database: "postgres"},
+	}, asset, logger, &Support{})
+}
+
+func (s *DBFixtureSuite) TestReadFixtures() {
+	fixtures, err := s.db.readFixtures("primary")
+	s.Nil(err)
+	s.Equal([]dbFixture{
+		{
+			table: "posts",
+			rows: []map[string]interface{}{
+				{"title": "Hello World", "user_id": 1},
+			},
+		},
+		{
+			table: "users",
+			rows: []map[string]interface{}{
+				{"id": 1, "email": "john@appist.io", "username": "john", "created_at": "2020-01-01 00:00:00"},
+				{"id": 2, "email": "mary@appist.io", "username": "mary", "created_at": "2020-01-01 00:00:00"},
+			},
+		},
+	}, fixtures)
+
+	fixtures, err = s.db.readFixtures("primary", "users", "posts")
+	s.Nil(err)
+	s.Equal(2, len(fixtures))
+	s.Equal("users", fixtures[0].table)
+	s.Equal("posts", fixtures[1].table)
+
+	_, err = s.db.readFixtures("primary", "comments")
+	s.EqualError(err, "fixtures for 'comments' table in 'primary' database are not found")
+
+	_, err = s.db.readFixtures("invalid")
+	s.Contains(err.Error(), "unable to parse 'testdata/db/fixtures/invalid/users.yml'")
+
+	_, err = s.db.readFixtures("unknown")
+	s.NotNil(err)
+
+	_, err = NewDB(&DBConfig{}, nil, nil, &Support{}).readFixtures("primary")
+	s.EqualError(err, "asset is missing to load 'primary' database fixtures")
+}
+
+func (s *DBFixtureSuite) TestInsertFixtures() {
+	s.Nil(s.db.Connect())
+	defer s.db.Close()
+
+	err := s.db.RunInTransaction(func(tx *DBTx) error {
+		_, err := tx.Exec(`CREATE TEMPORARY TABLE users (id SERIAL PRIMARY KEY, email VARCHAR, username VARCHAR, created_at TIMESTAMP)`)
+		s.Nil(err)
+
+		fixtures, err := s.db.readFixtures("primary", "users")
+		s.Nil(err)
+		s.Nil(insertFixtures(tx, fixtures))
+
+		var email, username string
+		_, err = tx.QueryOne(pg.Scan(&email, &username), "SELECT email, username FROM users WHERE id = ?", 1)
+		s.Nil(err)
+		s.Equal("john@appist.io", email)
+		s.Equal("john", username)
+
+		// The sequence is reset to continue after the fixtures.
+		var id int
+		_, err = tx.QueryOne(pg.Scan(&id), "INSERT INTO users (email) VALUES ('jane@appist.io') RETURNING id")
+		s.Nil(err)
+		s.Equal(3, id)
+
+		// The fixtures with the `id` column which isn't backed by a sequence don't reset the sequence.
+		_, err = tx.Exec(`CREATE TEMPORARY TABLE fixture_tags (id VARCHAR PRIMARY KEY, name VARCHAR)`)
+		s.Nil(err)
+		s.Nil(insertFixtures(tx, []dbFixture{
+			{table: "fixture_tags", rows: []map[string]interface{}{{"id": "go", "name": "Go"}}},
+		}))
+
+		// The posts table doesn't exist.
+		fixtures, err = s.db.readFixtures("primary", "posts")
+		s.Nil(err)
+
+		err = insertFixtures(tx, fixtures)
+		s.Contains(err.Error(), "unable to insert fixtures into 'posts' table")
+
+		return errors.New("rollback")
+	})
+	s.EqualError(err, "rollback")
+}
+
+func TestDBFixtureSuite(t *testing.T) {
+	RunTestSuite(t, new(DBFixtureSuite))
+}
@@ -0,0 +1,705 @@
+package appy
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+type (
+	dbSchema struct {
+		Name        string
+		Constraints []dbSchemaConstraint
+		Extensions  []dbSchemaExtension
+		Functions   []dbSchemaFunction
+		Indexes     []dbSchemaIndex
+		Sequences   []dbSchemaSequence
+		Tables      []dbSchemaTable
+		Triggers    []dbSchemaTrigger
+		Types       []dbSchemaType
+		Views       []dbSchemaView
+	}
+
+	dbSchemaColumn struct {
+		Table    string
+		Name     string
+		DataType string
+		Default  string
+		Identity string
+		NotNull  bool
+	}
+
+	dbSchemaConstraint struct {
+		Table      string
+		Name       string
+		Type       string
+		Definition string
+	}
+
+	dbSchemaExtension struct {
+		Name   string
+		Schema string
+	}
+
+	dbSchemaFunction struct {
+		Name       string
+		Definition string
+	}
+
+	dbSchemaIndex struct {
+		Name       string
+		Table      string
+		Definition string
+	}
+
+	dbSchemaSequence struct {
+		Name        string
+		DataType    string
+		Start       int64
+		Increment   int64
+		Min         int64
+		Max         int64
+		Cycle       bool
+		OwnedTable  string
+		OwnedColumn string
+	}
+
+	dbSchemaTable struct {
+		Name    string
+		Columns []dbSchemaColumn
+	}
+
+	dbSchemaTrigger struct {
+		Name       string
+		Definition string
+	}
+
+	// dbSchemaType is an enum, composite or domain type, i.e. Type is "e", "c" or "d".
+	dbSchemaType struct {
+		Name       string
+		Type       string
+		Definition string
+	}
+
+	dbSchemaView struct {
+		Name         string
+		Definition   string
+		DependsOn    []string
+		Materialized bool
+	}
+
+	dbSchemaViewDependency struct {
+		View      string
+		DependsOn string
+	}
+)
+
+// dumpSchemaNative introspects the database schema via `pg_catalog` and returns the deterministic SQL which doesn't
+// depend on the `pg_dump` client version.
+func (db *DB) dumpSchemaNative() (string, error) {
+	schema, err := db.introspectSchema()
+	if err != nil {
+		return "", err
+	}
+
+	return schema.SQL(), nil
+}
+
+func (db *DB) introspectSchema() (*dbSchema, error) {
+	schema := &dbSchema{Name: db.config.SchemaSearchPath}
+
+	_, err := db.Query(&schema.Extensions, `
+		SELECT quote_ident(e.extname) AS name, quote_ident(n.nspname) AS schema
+		FROM pg_extension e
+		JOIN pg_namespace n ON n.oid = e.extnamespace
+		WHERE n.nspname = ? AND e.extname <> 'plpgsql'
+		ORDER BY e.extname
+	`, db.config.SchemaSearchPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// The types and functions that belong to the extensions are excluded as they are created by `CREATE EXTENSION`.
+	_, err = db.Query(&schema.Types, `
+		SELECT
+			quote_ident(t.typname) AS name,
+			t.typtype AS "type",
+			CASE t.typtype
+				WHEN 'e' THEN (
+					SELECT string_agg(quote_literal(e.enumlabel), E',\n    ' ORDER BY e.enumsortorder)
+					FROM pg_enum e
+					WHERE e.enumtypid = t.oid
+				)
+				WHEN 'c' THEN (
+					SELECT string_agg(quote_ident(a.attname) || ' ' || format_type(a.atttypid, a.atttypmod), E',\n    ' ORDER BY a.attnum)
+					FROM pg_attribute a
+					WHERE a.attrelid = t.typrelid AND a.attnum > 0 AND NOT a.attisdropped
+				)
+				ELSE format_type(t.typbasetype, t.typtypmod)
+					|| COALESCE(' DEFAULT ' || t.typdefault, '')
+					|| CASE WHEN t.typnotnull THEN ' NOT NULL' ELSE '' END
+					|| COALESCE((
+						SELECT string_agg(' CONSTRAINT ' || quote_ident(con.conname) || ' ' || pg_get_constraintdef(con.oid), '' ORDER BY con.conname)
+						FROM pg_constraint con
+						WHERE con.contypid = t.oid
+					), '')
+			END AS definition
+		FROM pg_type t
+		JOIN pg_namespace n ON n.oid = t.typnamespace
+		LEFT JOIN pg_class c ON c.oid = t.typrelid
+		WHERE n.nspname = ? AND (t.typtype IN ('e', 'd') OR (t.typtype = 'c' AND c.relkind = 'c'))
+			AND NOT EXISTS (SELECT 1 FROM pg_depend d WHERE d.objid = t.oid AND d.deptype = 'e')
+		ORDER BY CASE t.typtype WHEN 'e' THEN 0 WHEN 'c' THEN 1 ELSE 2 END, t.typname
+	`, db.config.SchemaSearchPath)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Query(&schema.Functions, `
+		SELECT
+			quote_ident(p.proname) || '(' || pg_get_function_identity_arguments(p.oid) || ')' AS name,
+			pg_get_functiondef(p.oid) AS definition
+		FROM pg_proc p
+		JOIN pg_namespace n ON n.oid = p.pronamespace
+		WHERE n.nspname = ? AND NOT EXISTS (SELECT 1 FROM pg_aggregate a WHERE a.aggfnoid = p.oid)
+			AND NOT EXISTS (SELECT 1 FROM pg_depend d WHERE d.objid = p.oid AND d.deptype = 'e')
+		ORDER BY p.proname, pg_get_function_identity_arguments(p.oid)
+	`, db.config.SchemaSearchPath)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Query(&schema.Sequences, `
+		SELECT
+			quote_ident(c.relname) AS name,
+			format_type(s.seqtypid, NULL) AS data_type,
+			s.seqstart AS start,
+			s.seqincrement AS increment,
+			s.seqmin AS min,
+			s.seqmax AS max,
+			s.seqcycle AS cycle,
+			COALESCE(quote_ident(t.relname), '') AS owned_table,
+			COALESCE(quote_ident(a.attname), '') AS owned_column
+		FROM pg_sequence s
+		JOIN pg_class c ON c.oid = s.seqrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		LEFT JOIN pg_depend d ON d.objid = c.oid AND d.classid = 'pg_class'::regclass AND d.deptype IN ('a', 'i')
+		LEFT JOIN pg_class t ON t.oid = d.refobjid
+		LEFT JOIN pg_attribute a ON a.attrelid = d.refobjid AND a.attnum = d.refobjsubid
+		WHERE n.nspname = ? AND COALESCE(d.deptype, 'a') <> 'i'
+		ORDER BY c.relname
+	`, db.config.SchemaSearchPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var columns []dbSchemaColumn
+	_, err = db.Query(&columns, `
+		SELECT
+			quote_ident(c.relname) AS "table",
+			quote_ident(a.attname) AS name,
+			format_type(a.atttypid, a.atttypmod) AS data_type,
+			COALESCE(pg_get_expr(ad.adbin, ad.adrelid), '') AS "default",
+			a.attidentity AS identity,
+			a.attnotnull AS not_null
+		FROM pg_attribute a
+		JOIN pg_class c ON c.oid = a.attrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		LEFT JOIN pg_attrdef ad ON ad.adrelid = a.attrelid AND ad.adnum = a.attnum
+		WHERE n.nspname = ? AND c.relkind IN ('r', 'p') AND a.attnum > 0 AND NOT a.attisdropped
+		ORDER BY c.relname, a.attnum
+	`, db.config.SchemaSearchPath)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, column := range columns {
+		if len(schema.Tables) < 1 || schema.Tables[len(schema.Tables)-1].Name != column.Table {
+			schema.Tables = append(schema.Tables, dbSchemaTable{Name: column.Table})
+		}
+
+		table := &schema.Tables[len(schema.Tables)-1]
+		table.Columns = append(table.Columns, column)
+	}
+
+	_, err = db.Query(&schema.Constraints, `
+		SELECT
+			quote_ident(c.relname) AS "table",
+			quote_ident(con.conname) AS name,
+			con.contype AS "type",
+			pg_get_constraintdef(con.oid) AS definition
+		FROM pg_constraint con
+		JOIN pg_class c ON c.oid = con.conrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = ? AND con.contype IN ('p', 'u', 'c', 'x', 'f')
+		ORDER BY c.relname, con.conname
+	`, db.config.SchemaSearchPath)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Query(&schema.Indexes, `
+		SELECT quote_ident(ic.relname) AS name, quote_ident(t.relname) AS "table", pg_get_indexdef(i.indexrelid) AS definition
+		FROM pg_index i
+		JOIN pg_class ic ON ic.oid = i.indexrelid
+		JOIN pg_class t ON t.oid = i.indrelid
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		WHERE n.nspname = ? AND NOT EXISTS (SELECT 1 FROM pg_constraint con WHERE con.conindid = i.indexrelid)
+		ORDER BY t.relname, ic.relname
+	`, db.config.SchemaSearchPath)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Query(&schema.Views, `
+		SELECT
+			quote_ident(c.relname) AS name,
+			pg_get_viewdef(c.oid, true) AS definition,
+			c.relkind = 'm' AS materialized
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = ? AND c.relkind IN ('v', 'm')
+		ORDER BY c.relname
+	`, db.config.SchemaSearchPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var dependencies []dbSchemaViewDependency
+	_, err = db.Query(&dependencies, `
+		SELECT DISTINCT quote_ident(v.relname) AS view, quote_ident(d.relname) AS depends_on
+		FROM pg_depend dep
+		JOIN pg_rewrite r ON r.oid = dep.objid
+		JOIN pg_class v ON v.oid = r.ev_class
+		JOIN pg_class d ON d.oid = dep.refobjid
+		JOIN pg_namespace n ON n.oid = v.relnamespace
+		WHERE n.nspname = ? AND v.relkind IN ('v', 'm') AND d.relkind IN ('v', 'm') AND v.oid <> d.oid
+		ORDER BY view, depends_on
+	`, db.config.SchemaSearchPath)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, dependency := range dependencies {
+		for i := range schema.Views {
+			if schema.Views[i].Name == dependency.View {
+				schema.Views[i].DependsOn = append(schema.Views[i].DependsOn, dependency.DependsOn)
+			}
+		}
+	}
+
+	_, err = db.Query(&schema.Triggers, `
+		SELECT quote_ident(t.tgname) AS name, pg_get_triggerdef(t.oid, true) AS definition
+		FROM pg_trigger t
+		JOIN pg_class c ON c.oid = t.tgrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = ? AND NOT t.tgisinternal
+		ORDER BY c.relname, t.tgname
+	`, db.config.SchemaSearchPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return schema, nil
+}
+
+// SQL returns the schema in SQL which is ordered by the object type and then the object name, except the views which
+// are ordered by their dependencies first, so that the output is deterministic.
+func (s *dbSchema) SQL() string {
+	var stmts []string
+	schema := s.Name
+
+	for _, ext := range s.Extensions {
+		stmts = append(stmts, fmt.Sprintf("CREATE EXTENSION IF NOT EXISTS %s WITH SCHEMA %s;", ext.Name, ext.Schema))
+	}
+
+	stmts = append(stmts, fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS %s;", schema))
+
+	for _, typ := range s.Types {
+		switch typ.Type {
+		case "e":
+			stmts = append(stmts, fmt.Sprintf("CREATE TYPE %s.%s AS ENUM (\n    %s\n);", schema, typ.Name, typ.Definition))
+		case "c":
+			stmts = append(stmts, fmt.Sprintf("CREATE TYPE %s.%s AS (\n    %s\n);", schema, typ.Name, typ.Definition))
+		case "d":
+			stmts = append(stmts, fmt.Sprintf("CREATE DOMAIN %s.%s AS %s;", schema, typ.Name, typ.Definition))
+		}
+	}
+
+	// The function bodies aren't validated as they might refer to the tables that are created afterwards.
+	if len(s.Functions) > 0 {
+		stmts = append(stmts, "SET check_function_bodies = false;")
+	}
+
+	for _, fn := range s.Functions {
+		stmts = append(stmts, strings.TrimSpace(fn.Definition)+";")
+	}
+
+	for _, seq := range s.Sequences {
+		cycle := "NO CYCLE"
+		if seq.Cycle {
+			cycle = "CYCLE"
+		}
+
+		stmts = append(stmts, fmt.Sprintf(
+			"CREATE SEQUENCE IF NOT EXISTS %s.%s\n    AS %s\n    START WITH %d\n    INCREMENT BY %d\n    MINVALUE %d\n    MAXVALUE %d\n    %s;",
+			schema, seq.Name, seq.DataType, seq.Start, seq.Increment, seq.Min, seq.Max, cycle,
+		))
+	}
+
+	for _, table := range s.Tables {
+		var columns []string
+		for _, column := range table.Columns {
+			def := column.Name + " " + column.DataType
+
+			switch column.Identity {
+			case "a":
+				def += " GENERATED ALWAYS AS IDENTITY"
+			case "d":
+				def += " GENERATED BY DEFAULT AS IDENTITY"
+			}
+
+			if column.Default != "" {
+				def += " DEFAULT " + column.Default
+			}
+
+			if column.NotNull {
+				def += " NOT NULL"
+			}
+
+			columns = append(columns, "    "+def)
+		}
+
+		stmts = append(stmts, fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s.%s (\n%s\n);", schema, table.Name, strings.Join(columns, ",\n")))
+	}
+
+	for _, seq := range s.Sequences {
+		if seq.OwnedTable == "" {
+			continue
+		}
+
+		stmts = append(stmts, fmt.Sprintf("ALTER SEQUENCE %s.%s OWNED BY %s.%s.%s;", schema, seq.Name, schema, seq.OwnedTable, seq.OwnedColumn))
+	}
+
+	// The foreign keys are added last as they depend on the other tables' primary keys or unique constraints.
+	for _, fk := range []bool{false, true} {
+		for _, con := range s.Constraints {
+			if (con.Type == "f") != fk {
+				continue
+			}
+
+			stmts = append(stmts, fmt.Sprintf("ALTER TABLE ONLY %s.%s\n    ADD CONSTRAINT %s %s;", schema, con.Table, con.Name, con.Definition))
+		}
+	}
+
+	for _, idx := range s.Indexes {
+		stmts = append(stmts, idx.Definition+";")
+	}
+
+	for _, view := range s.sortedViews() {
+		def := strings.TrimSuffix(strings.TrimSpace(view.Definition), ";")
+
+		if view.Materialized {
+			stmts = append(stmts, fmt.Sprintf("CREATE MATERIALIZED VIEW IF NOT EXISTS %s.%s AS\n%s\nWITH NO DATA;", schema, view.Name, def))
+			continue
+		}
+
+		stmts = append(stmts, fmt.Sprintf("CREATE OR REPLACE VIEW %s.%s AS\n%s;", schema, view.Name, def))
+	}
+
+	for _, trigger := range s.Triggers {
+		stmts = append(stmts, trigger.Definition+";")
+	}
+
+	return strings.Join(stmts, "\n\n")
+}
+
+// sortedViews returns the views which are ordered by their dependencies and then their names.
+func (s *dbSchema) sortedViews() []dbSchemaView {
+	names := map[string]bool{}
+	for _, view := range s.Views {
+		names[view.Name] = true
+	}
+
+	var sorted []dbSchemaView
+	added := map[string]bool{}
+	for len(sorted) < len(s.Views) {
+		next := -1
+		for i, view := range s.Views {
+			if added[view.Name] {
+				continue
+			}
+
+			ready := true
+			for _, dependency := range view.DependsOn {
+				if names[dependency] && !added[dependency] {
+					ready = false
+					break
+				}
+			}
+
+			if ready {
+				next = i
+				break
+			}
+		}
+
+		// The circular dependency isn't possible in PostgreSQL, but the remaining views are added as is just in case.
+		if next < 0 {
+			for _, view := range s.Views {
+				if !added[view.Name] {
+					sorted = append(sorted, view)
+					added[view.Name] = true
+				}
+			}
+
+			break
+		}
+
+		sorted = append(sorted, s.Views[next])
+		added[s.Views[next].Name] = true
+	}
+
+	return sorted
+}
+
+var (
+	dbSchemaIndexRegex   = regexp.MustCompile(`(?m)^CREATE (?:UNIQUE )?INDEX (?:CONCURRENTLY )?(?:IF NOT EXISTS )?([\w"]+) ON (?:ONLY )?(?:[\w"]+\.)?([\w"]+) `)
+	dbSchemaTableRegex   = regexp.MustCompile(`(?ms)^CREATE (?:UNLOGGED )?TABLE (?:IF NOT EXISTS )?(?:[\w"]+\.)?([\w"]+) \((.*?)\n\)[^;]*;`)
+	dbSchemaValueRegex   = regexp.MustCompile(`'([^']+)'`)
+	dbSchemaVersionRegex = regexp.MustCompile(`(?s)INSERT INTO [\w".]+ \(version\) VALUES\s*(.*?);`)
+)
+
+// DBSchemaDiff contains the differences between the live database and the dumped schema in
+// `db/migrate/<name>/schema.go`, i.e. "missing" means it is in the dumped schema but not in the live database and
+// "extra" means it is in the live database but not in the dumped schema.
+type DBSchemaDiff struct {
+	MissingTables     []string
+	ExtraTables       []string
+	MissingColumns    []string
+	ExtraColumns      []string
+	MissingIndexes    []string
+	ExtraIndexes      []string
+	UnappliedVersions []string
+	UnknownVersions   []string
+}
+
+// CheckSchema compares the live database against the dumped schema and the applied `schema_migrations` versions
+// against the dumped and registered migrations. The ignored tables with their columns and indexes are excluded from
+// the comparison, e.g. the postgres session store's table which is created on demand instead of by a migration.
+func (db *DB) CheckSchema(ignoredTables ...string) (*DBSchemaDiff, error) {
+	if db.schema == "" {
+		return nil, fmt.Errorf("schema for '%s' database is not dumped yet", db.config.Database)
+	}
+
+	live, err := db.introspectSchema()
+	if err != nil {
+		return nil, err
+	}
+
+	liveVersions, err := db.migratedVersions()
+	if err != nil {
+		return nil, err
+	}
+
+	dumped, dumpedVersions := parseDBSchema(db.schema)
+
+	var knownVersions []string
+	for _, m := range db.migrations {
+		knownVersions = append(knownVersions, m.Version)
+	}
+
+	return diffDBSchema(dumped.withoutTables(ignoredTables), live.withoutTables(ignoredTables),
+		append(dumpedVersions, knownVersions...), liveVersions), nil
+}
+
+// IsEmpty returns true if there is no difference.
+func (d *DBSchemaDiff) IsEmpty() bool {
+	return len(d.Report()) < 1
+}
+
+// Report returns the differences in human readable form.
+func (d *DBSchemaDiff) Report() []string {
+	var report []string
+	for _, item := range []struct {
+		label  string
+		values []string
+	}{
+		{"missing table", d.MissingTables},
+		{"extra table", d.ExtraTables},
+		{"missing column", d.MissingColumns},
+		{"extra column", d.ExtraColumns},
+		{"missing index", d.MissingIndexes},
+		{"extra index", d.ExtraIndexes},
+		{"unapplied version", d.UnappliedVersions},
+		{"unknown version", d.UnknownVersions},
+	} {
+		for _, value := range item.values {
+			report = append(report, fmt.Sprintf("%s '%s'", item.label, value))
+		}
+	}
+
+	return report
+}
+
+// parseDBSchema parses the tables, columns and indexes, and the `schema_migrations` versions from the dumped schema
+// which is generated by either the "native" or "pg_dump" format.
+func parseDBSchema(sql string) (*dbSchema, []string) {
+	schema := &dbSchema{}
+
+	for _, match := range dbSchemaTableRegex.FindAllStringSubmatch(sql, -1) {
+		table := dbSchemaTable{Name: match[1]}
+
+		for _, line := range strings.Split(match[2], "\n") {
+			fields := strings.Fields(strings.TrimSuffix(strings.TrimSpace(line), ","))
+			if len(fields) < 1 {
+				continue
+			}
+
+			switch strings.ToUpper(fields[0]) {
+			case "CONSTRAINT", "PRIMARY", "UNIQUE", "CHECK", "FOREIGN", "EXCLUDE":
+				continue
+			}
+
+			table.Columns = append(table.Columns, dbSchemaColumn{Table: table.Name, Name: fields[0]})
+		}
+
+		schema.Tables = append(schema.Tables, table)
+	}
+
+	for _, match := range dbSchemaIndexRegex.FindAllStringSubmatch(sql, -1) {
+		schema.Indexes = append(schema.Indexes, dbSchemaIndex{Name: match[1], Table: match[2]})
+	}
+
+	var versions []string
+	if match := dbSchemaVersionRegex.FindStringSubmatch(sql); match != nil {
+		for _, version := range dbSchemaValueRegex.FindAllStringSubmatch(match[1], -1) {
+			versions = append(versions, version[1])
+		}
+	}
+
+	return schema, versions
+}
+
+// diffDBSchema compares the live schema against the dumped schema, and the live versions against the known versions
+// which are the dumped and registered migration versions.
+func diffDBSchema(dumped, live *dbSchema, knownVersions, liveVersions []string) *DBSchemaDiff {
+	diff := &DBSchemaDiff{}
+	diff.MissingTables, diff.ExtraTables = diffStrings(dumped.tableNames(), live.tableNames())
+	diff.MissingColumns, diff.ExtraColumns = diffStrings(dumped.columnNames(), live.columnNames())
+	diff.MissingIndexes, diff.ExtraIndexes = diffStrings(dumped.indexNames(), live.indexNames())
+	diff.UnappliedVersions, diff.UnknownVersions = diffStrings(knownVersions, liveVersions)
+
+	// The columns of the missing/extra tables are already reported as the missing/extra tables.
+	diff.MissingColumns = filterColumns(diff.MissingColumns, diff.MissingTables)
+	diff.ExtraColumns = filterColumns(diff.ExtraColumns, diff.ExtraTables)
+
+	return diff
+}
+
+func (s *dbSchema) columnNames() []string {
+	var names []string
+	for _, table := range s.Tables {
+		for _, column := range table.Columns {
+			names = append(names, table.Name+"."+column.Name)
+		}
+	}
+
+	return names
+}
+
+func (s *dbSchema) indexNames() []string {
+	var names []string
+	for _, index := range s.Indexes {
+		names = append(names, index.Name)
+	}
+
+	return names
+}
+
+func (s *dbSchema) tableNames() []string {
+	var names []string
+	for _, table := range s.Tables {
+		names = append(names, table.Name)
+	}
+
+	return names
+}
+
+// withoutTables returns the schema without the tables, and their columns and indexes.
+func (s *dbSchema) withoutTables(tables []string) *dbSchema {
+	if len(tables) < 1 {
+		return s
+	}
+
+	ignored := map[string]bool{}
+	for _, table := range tables {
+		ignored[table] = true
+	}
+
+	schema := &dbSchema{}
+	for _, table := range s.Tables {
+		if !ignored[table.Name] {
+			schema.Tables = append(schema.Tables, table)
+		}
+	}
+
+	for _, index := range s.Indexes {
+		if !ignored[index.Table] {
+			schema.Indexes = append(schema.Indexes, index)
+		}
+	}
+
+	return schema
+}
+
+// diffStrings returns the sorted unique values that are only in a and only in b.
+func diffStrings(a, b []string) ([]string, []string) {
+	inA, inB := map[string]bool{}, map[string]bool{}
+	for _, val := range a {
+		inA[val] = true
+	}
+
+	for _, val := range b {
+		inB[val] = true
+	}
+
+	var onlyA, onlyB []string
+	for val := range inA {
+		if !inB[val] {
+			onlyA = append(onlyA, val)
+		}
+	}
+
+	for val := range inB {
+		if !inA[val] {
+			onlyB = append(onlyB, val)
+		}
+	}
+
+	sort.Strings(onlyA)
+	sort.Strings(onlyB)
+	return onlyA, onlyB
+}
+
+func filterColumns(columns, tables []string) []string {
+	var filtered []string
+	for _, column := range columns {
+		table := column[:strings.LastIndex(column, ".")]
+
+		skip := false
+		for _, t := range tables {
+			if t == table {
+				skip = true
+				break
+			}
+		}
+
+		if !skip {
+			filtered = append(filtered, column)
+		}
+	}
+
+	return filtered
+}
@@ -0,0 +1,211 @@
+package appy
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"time"
+)
+
+const dbSSLRequestCode = 80877103
+
+// setupDBSSL configures the dialer that negotiates SSL with the server following DB_SSLMODE_<NAME> like libpq:
+//
+//   - disable: only tries a non-SSL connection
+//   - allow: only tries a non-SSL connection as go-pg doesn't retry with SSL when the server rejects it
+//   - prefer: tries an SSL connection first and falls back to a non-SSL connection if the server doesn't support SSL
+//   - require: only tries an SSL connection without verifying the server certificate, unless DB_SSLROOTCERT_<NAME> is
+//     specified which then verifies it like verify-ca
+//   - verify-ca: only tries an SSL connection and verifies the server certificate is signed by a trusted CA
+//   - verify-full: only tries an SSL connection and verifies the server certificate is signed by a trusted CA and
+//     its host name matches the DB_ADDR_<NAME>'s host
+//
+// The SSL isn't used with the unix socket which is the same as libpq. Note that go-pg's TLSConfig is not used since
+// it can't fall back to a non-SSL connection for "prefer".
+func setupDBSSL(config *DBConfig, asset *Asset) error {
+	config.TLSConfig = nil
+
+	if config.Network == "unix" {
+		return nil
+	}
+
+	switch config.SSLMode {
+	case "", "disable", "allow":
+		return nil
+	}
+
+	tlsConfig, err := newDBTLSConfig(config, asset)
+	if err != nil {
+		return err
+	}
+
+	dialer := &net.Dialer{Timeout: config.DialTimeout, KeepAlive: 5 * time.Minute}
+	config.Dialer = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dialer.DialContext(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+
+		return negotiateDBSSL(conn, config.SSLMode, tlsConfig, config.DialTimeout)
+	}
+
+	return nil
+}
+
+// newDBTLSConfig builds the TLS config with the DB_SSLROOTCERT_<NAME>, DB_SSLCERT_<NAME> and DB_SSLKEY_<NAME> files
+// which are read through the asset, or from the filesystem if they are not found in the asset. The system's root CAs
+// are used to verify the server certificate if DB_SSLROOTCERT_<NAME> is not specified.
+func newDBTLSConfig(config *DBConfig, asset *Asset) (*tls.Config, error) {
+	// The server certificate is verified by VerifyPeerCertificate for verify-ca since the host name isn't checked.
+	tlsConfig := &tls.Config{InsecureSkipVerify: true} //nolint
+
+	if config.SSLCert != "" || config.SSLKey != "" {
+		if config.SSLCert == "" || config.SSLKey == "" {
+			return nil, errors.New("both sslcert and sslkey must be specified for the client certificate")
+		}
+
+		certPEM, err := readDBSSLFile(asset, config.SSLCert)
+		if err != nil {
+			return nil, err
+		}
+
+		keyPEM, err := readDBSSLFile(asset, config.SSLKey)
+		if err != nil {
+			return nil, err
+		}
+
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load the client certificate '%s': %s", config.SSLCert, err)
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	var roots *x509.CertPool
+	if config.SSLRootCert != "" {
+		rootPEM, err := readDBSSLFile(asset, config.SSLRootCert)
+		if err != nil {
+			return nil, err
+		}
+
+		roots = x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(rootPEM) {
+			return nil, fmt.Errorf("unable to load the root certificate '%s'", config.SSLRootCert)
+		}
+	}
+
+	mode := config.SSLMode
+	if (mode == "prefer" || mode == "require") && roots != nil {
+		mode = "verify-ca"
+	}
+
+	switch mode {
+	case "verify-ca":
+		tlsConfig.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			return verifyDBCertificateChain(rawCerts, roots)
+		}
+	case "verify-full":
+		host, _, err := net.SplitHostPort(config.Addr)
+		if err != nil {
+			host = config.Addr
+		}
+
+		tlsConfig.InsecureSkipVerify = false
+		tlsConfig.RootCAs = roots
+		tlsConfig.ServerName = host
+	}
+
+	return tlsConfig, nil
+}
+
+// negotiateDBSSL sends the SSLRequest message to the server and upgrades the connection to SSL if the server accepts
+// it.
+func negotiateDBSSL(conn net.Conn, mode string, tlsConfig *tls.Config, timeout time.Duration) (net.Conn, error) {
+	if timeout > 0 {
+		if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	request := make([]byte, 8)
+	binary.BigEndian.PutUint32(request[0:4], 8)
+	binary.BigEndian.PutUint32(request[4:8], dbSSLRequestCode)
+	if _, err := conn.Write(request); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	response := make([]byte, 1)
+	if _, err := io.ReadFull(conn, response); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	switch response[0] {
+	case 'S':
+		tlsConn := tls.Client(conn, tlsConfig)
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return nil, err
+		}
+
+		conn = tlsConn
+	case 'N':
+		if mode != "prefer" {
+			conn.Close()
+			return nil, errors.New("server does not support SSL, but SSL was required")
+		}
+	default:
+		conn.Close()
+		return nil, fmt.Errorf("received invalid response to SSL negotiation: %q", response[0])
+	}
+
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+func verifyDBCertificateChain(rawCerts [][]byte, roots *x509.CertPool) error {
+	if len(rawCerts) < 1 {
+		return errors.New("server certificate is missing")
+	}
+
+	certs := make([]*x509.Certificate, len(rawCerts))
+	for i, rawCert := range rawCerts {
+		cert, err := x509.ParseCertificate(rawCert)
+		if err != nil {
+			return err
+		}
+
+		certs[i] = cert
+	}
+
+	opts := x509.VerifyOptions{Roots: roots, Intermediates: x509.NewCertPool()}
+	for _, cert := range certs[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+
+	_, err := certs[0].Verify(opts)
+	return err
+}
+
+func readDBSSLFile(asset *Asset, path string) ([]byte, error) {
+	if asset != nil {
+		if content, err := asset.ReadFile(path); err == nil {
+			return content, nil
+		}
+	}
+
+	return ioutil.ReadFile(path)
+}
@@ -0,0 +1,223 @@
+package appy
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-pg/pg/v9"
+)
+
+type DBSSLSuite struct {
+	TestSuite
+}
+
+// serve starts a fake PostgreSQL server which replies the SSLRequest with the response and runs the TLS handshake if
+// the response is 'S'.
+func (s *DBSSLSuite) serve(response byte, clientAuth tls.ClientAuthType) (string, chan error) {
+	cert, err := tls.LoadX509KeyPair("testdata/db/ssl/server.crt", "testdata/db/ssl/server.key")
+	s.Nil(err)
+
+	caPEM, err := ioutil.ReadFile("testdata/db/ssl/ca.crt")
+	s.Nil(err)
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AppendCertsFromPEM(caPEM)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	s.Nil(err)
+
+	errCh := make(chan error, 1)
+	go func() {
+		defer listener.Close()
+
+		conn, err := listener.Accept()
+		if err != nil {
+			errCh <- err
+			return
+		}
+		defer conn.Close()
+
+		request := make([]byte, 8)
+		if _, err := io.ReadFull(conn, request); err != nil {
+			errCh <- err
+			return
+		}
+
+		if binary.BigEndian.Uint32(request[4:8]) != dbSSLRequestCode {
+			errCh <- io.ErrUnexpectedEOF
+			return
+		}
+
+		if _, err := conn.Write([]byte{response}); err != nil {
+			errCh <- err
+			return
+		}
+
+		if response != 'S' {
+			errCh <- nil
+			return
+		}
+
+		errCh <- tls.Server(conn, &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			ClientAuth:   clientAuth,
+			ClientCAs:    clientCAs,
+		}).Handshake()
+	}()
+
+	return listener.Addr().String(), errCh
+}
+
+func (s *DBSSLSuite) dial(config *DBConfig) (net.Conn, error) {
+	config.Network = "tcp"
+	config.DialTimeout = time.Second
+	s.Nil(setupDBSSL(config, nil))
+	s.NotNil(config.Dialer)
+
+	return config.Dialer(context.Background(), "tcp", config.Addr)
+}
+
+func (s *DBSSLSuite) TestSetupDBSSLWithoutSSL() {
+	for _, mode := range []string{"", "disable", "allow"} {
+		config := &DBConfig{SSLMode: mode}
+		s.Nil(setupDBSSL(config, nil))
+		s.Nil(config.Dialer)
+		s.Nil(config.TLSConfig)
+	}
+
+	config := &DBConfig{SSLMode: "require"}
+	config.Network = "unix"
+	s.Nil(setupDBSSL(config, nil))
+	s.Nil(config.Dialer)
+}
+
+func (s *DBSSLSuite) TestSetupDBSSLWithInvalidFiles() {
+	config := &DBConfig{SSLMode: "require", SSLKey: "testdata/db/ssl/client.key"}
+	s.EqualError(setupDBSSL(config, nil), "both sslcert and sslkey must be specified for the client certificate")
+
+	config = &DBConfig{SSLMode: "require", SSLCert: "testdata/db/ssl/missing.crt", SSLKey: "testdata/db/ssl/client.key"}
+	s.Contains(setupDBSSL(config, nil).Error(), "no such file or directory")
+
+	config = &DBConfig{SSLMode: "require", SSLCert: "testdata/db/ssl/client.crt", SSLKey: "testdata/db/ssl/server.key"}
+	s.Contains(setupDBSSL(config, nil).Error(), "unable to load the client certificate 'testdata/db/ssl/client.crt'")
+
+	config = &DBConfig{SSLMode: "verify-ca", SSLRootCert: "testdata/db/ssl/client.key"}
+	s.EqualError(setupDBSSL(config, nil), "unable to load the root certificate 'testdata/db/ssl/client.key'")
+
+	config = &DBConfig{SSLMode: "verify-ca", SSLRootCert: "testdata/db/ssl/ca.crt"}
+	s.Nil(setupDBSSL(config, NewAsset(nil, nil, "")))
+}
+
+func (s *DBSSLSuite) TestPrefer() {
+	addr, errCh := s.serve('S', tls.NoClientCert)
+	conn, err := s.dial(&DBConfig{SSLMode: "prefer", Options: pg.Options{Addr: addr}})
+	s.Nil(err)
+	s.IsType(&tls.Conn{}, conn)
+	s.Nil(<-errCh)
+	conn.Close()
+
+	addr, errCh = s.serve('N', tls.NoClientCert)
+	conn, err = s.dial(&DBConfig{SSLMode: "prefer", Options: pg.Options{Addr: addr}})
+	s.Nil(err)
+	s.IsType(&net.TCPConn{}, conn)
+	s.Nil(<-errCh)
+	conn.Close()
+
+	addr, errCh = s.serve('E', tls.NoClientCert)
+	_, err = s.dial(&DBConfig{SSLMode: "prefer", Options: pg.Options{Addr: addr}})
+	s.EqualError(err, "received invalid response to SSL negotiation: 'E'")
+	s.Nil(<-errCh)
+
+	// The root certificate is verified like libpq if it is specified.
+	addr, errCh = s.serve('S', tls.NoClientCert)
+	_, err = s.dial(&DBConfig{SSLMode: "prefer", SSLRootCert: "testdata/db/ssl/other-ca.crt", Options: pg.Options{Addr: addr}})
+	s.Contains(err.Error(), "certificate signed by unknown authority")
+	s.NotNil(<-errCh)
+}
+
+func (s *DBSSLSuite) TestRequire() {
+	addr, errCh := s.serve('S', tls.NoClientCert)
+	conn, err := s.dial(&DBConfig{SSLMode: "require", Options: pg.Options{Addr: addr}})
+	s.Nil(err)
+	s.IsType(&tls.Conn{}, conn)
+	s.Nil(<-errCh)
+	conn.Close()
+
+	addr, errCh = s.serve('N', tls.NoClientCert)
+	_, err = s.dial(&DBConfig{SSLMode: "require", Options: pg.Options{Addr: addr}})
+	s.EqualError(err, "server does not support SSL, but SSL was required")
+	s.Nil(<-errCh)
+
+	addr, errCh = s.serve('S', tls.NoClientCert)
+	conn, err = s.dial(&DBConfig{SSLMode: "require", SSLRootCert: "testdata/db/ssl/ca.crt", Options: pg.Options{Addr: addr}})
+	s.Nil(err)
+	s.Nil(<-errCh)
+	conn.Close()
+
+	addr, errCh = s.serve('S', tls.NoClientCert)
+	_, err = s.dial(&DBConfig{SSLMode: "require", SSLRootCert: "testdata/db/ssl/other-ca.crt", Options: pg.Options{Addr: addr}})
+	s.Contains(err.Error(), "certificate signed by unknown authority")
+	s.NotNil(<-errCh)
+}
+
+func (s *DBSSLSuite) TestVerifyCA() {
+	// The server certificate is issued for "localhost" whereas the host name isn't verified.
+	addr, errCh := s.serve('S', tls.NoClientCert)
+	conn, err := s.dial(&DBConfig{SSLMode: "verify-ca", SSLRootCert: "testdata/db/ssl/ca.crt", Options: pg.Options{Addr: addr}})
+	s.Nil(err)
+	s.Nil(<-errCh)
+	conn.Close()
+
+	addr, errCh = s.serve('S', tls.NoClientCert)
+	_, err = s.dial(&DBConfig{SSLMode: "verify-ca", SSLRootCert: "testdata/db/ssl/other-ca.crt", Options: pg.Options{Addr: addr}})
+	s.Contains(err.Error(), "certificate signed by unknown authority")
+	s.NotNil(<-errCh)
+
+	addr, errCh = s.serve('S', tls.RequireAndVerifyClientCert)
+	conn, err = s.dial(&DBConfig{
+		SSLMode:     "verify-ca",
+		SSLRootCert: "testdata/db/ssl/ca.crt",
+		SSLCert:     "testdata/db/ssl/client.crt",
+		SSLKey:      "testdata/db/ssl/client.key",
+		Options:     pg.Options{Addr: addr},
+	})
+	s.Nil(err)
+	s.Nil(<-errCh)
+	conn.Close()
+
+	addr, errCh = s.serve('S', tls.RequireAndVerifyClientCert)
+	conn, err = s.dial(&DBConfig{SSLMode: "verify-ca", SSLRootCert: "testdata/db/ssl/ca.crt", Options: pg.Options{Addr: addr}})
+	if err == nil {
+		// The client may finish its handshake before the server rejects the missing client certificate.
+		_, err = conn.Read(make([]byte, 1))
+		conn.Close()
+	}
+	s.NotNil(err)
+	s.NotNil(<-errCh)
+}
+
+func (s *DBSSLSuite) TestVerifyFull() {
+	addr, errCh := s.serve('S', tls.NoClientCert)
+	_, err := s.dial(&DBConfig{SSLMode: "verify-full", SSLRootCert: "testdata/db/ssl/ca.crt", Options: pg.Options{Addr: addr}})
+	s.Contains(err.Error(), "cannot validate certificate for 127.0.0.1")
+	s.NotNil(<-errCh)
+
+	addr, errCh = s.serve('S', tls.NoClientCert)
+	_, port, _ := net.SplitHostPort(addr)
+	config := &DBConfig{SSLMode: "verify-full", SSLRootCert: "testdata/db/ssl/ca.crt", Options: pg.Options{Addr: "localhost:" + port}}
+	conn, err := s.dial(config)
+	s.Nil(err)
+	s.Nil(<-errCh)
+	conn.Close()
+}
+
+func TestDBSSLSuite(t *testing.T) {
+	RunTestSuite(t, new(DBSSLSuite))
+}
@@ -6,6 +6,21 @@ var (
 	// ErrMissingMasterKey indicates the master key is not provided.
 	ErrMissingMasterKey = errors.New("master key is missing")
 
+	// ErrDBMigrationLocked indicates the database migration lock is held by another migrator.
+	ErrDBMigrationLocked = errors.New("database migration is locked by another migrator")
+
+	// ErrDBNotConnected indicates the database is not connected yet.
+	ErrDBNotConnected = errors.New("database is not connected")
+
+	// ErrMailCanceled indicates the email is canceled by the mailer interceptor.
+	ErrMailCanceled = errors.New("mail is canceled")
+
+	// ErrMissingMailerTransport indicates the mailer doesn't have a transport to deliver the email with.
+	ErrMissingMailerTransport = errors.New("mailer transport is not configured")
+
+	// ErrMissingMailerWorker indicates the mailer is initialized without a worker to deliver the email later.
+	ErrMissingMailerWorker = errors.New("mailer worker is missing")
+
 	// ErrNoEmbeddedAssets indicates the embedded asset is missing.
 	ErrNoEmbeddedAssets = errors.New("embedded asset is missing")
 
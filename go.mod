@@ -17,6 +17,7 @@ require (
 	github.com/gin-contrib/sessions v0.0.3
 	github.com/gin-gonic/gin v1.5.1-0.20200307022333-1d055af1bc15
 	github.com/go-pg/pg/v9 v9.1.3
+	github.com/go-playground/validator/v10 v10.2.0
 	github.com/gomodule/redigo v2.0.0+incompatible
 	github.com/gorilla/context v1.1.1
 	github.com/gorilla/securecookie v1.1.1
@@ -36,6 +37,7 @@ require (
 	github.com/spf13/cobra v0.0.6
 	github.com/stretchr/testify v1.5.1
 	github.com/vektah/gqlparser/v2 v2.0.1
+	github.com/vmihailenco/msgpack/v4 v4.3.7
 	go.uber.org/zap v1.14.0
 	golang.org/x/lint v0.0.0-20200130185559-910be7a94367 // indirect
 	golang.org/x/text v0.3.2
@@ -0,0 +1,78 @@
+package jobqueue
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type (
+	// Job is the unit of work that is pushed into the queue and processed by the worker.
+	Job struct {
+		ID         string    `json:"id"`
+		Name       string    `json:"name"`
+		Queue      string    `json:"queue"`
+		Payload    []byte    `json:"payload"`
+		Retry      int       `json:"retry"`
+		MaxRetry   int       `json:"max_retry"`
+		Error      string    `json:"error,omitempty"`
+		EnqueuedAt time.Time `json:"enqueued_at"`
+		FailedAt   time.Time `json:"failed_at,omitempty"`
+
+		// raw is the job as it was dequeued which is needed to remove it from the processing list on Ack.
+		raw []byte
+	}
+
+	// Queue provides an interface to implement various job queue backends.
+	Queue interface {
+		// Enqueue pushes the job into its queue so that it can be processed immediately.
+		Enqueue(job *Job) error
+
+		// Schedule stores the job so that it will only be pushed into its queue at the specified time.
+		Schedule(job *Job, at time.Time) error
+
+		// Dequeue pops the next job from the queues in the given priority order. It waits up to the timeout for a job
+		// to arrive and returns nil if there is none.
+		Dequeue(queues []string, timeout time.Duration) (*Job, error)
+
+		// Ack marks the dequeued job as done after it is processed, retried or killed so that it won't be pushed back
+		// into its queue.
+		Ack(job *Job) error
+
+		// Promote pushes the scheduled jobs that are due by now into their queues.
+		Promote(now time.Time) error
+
+		// Kill moves the job into the dead letter set which won't be retried anymore.
+		Kill(job *Job) error
+
+		// Pending returns the jobs that are waiting to be processed in the queue.
+		Pending(queue string) ([]*Job, error)
+
+		// Scheduled returns the jobs that are waiting for their scheduled time.
+		Scheduled() ([]*Job, error)
+
+		// Dead returns the jobs in the dead letter set.
+		Dead() ([]*Job, error)
+
+		// Close releases the resources held by the queue.
+		Close() error
+	}
+)
+
+// Decode unmarshals the job payload into v.
+func (j *Job) Decode(v interface{}) error {
+	return json.Unmarshal(j.Payload, v)
+}
+
+func marshalJob(job *Job) ([]byte, error) {
+	return json.Marshal(job)
+}
+
+func unmarshalJob(data []byte) (*Job, error) {
+	job := &Job{}
+	if err := json.Unmarshal(data, job); err != nil {
+		return nil, err
+	}
+
+	job.raw = data
+	return job, nil
+}
@@ -0,0 +1,181 @@
+package jobqueue
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+type (
+	// MemoryQueue stores the jobs in the process memory which is useful for testing and doesn't survive restarts.
+	MemoryQueue struct {
+		mu        sync.Mutex
+		queues    map[string][]*Job
+		scheduled []*scheduledJob
+		dead      []*Job
+		notify    chan struct{}
+	}
+
+	scheduledJob struct {
+		job *Job
+		at  time.Time
+	}
+)
+
+// NewMemoryQueue initializes a MemoryQueue instance.
+func NewMemoryQueue() Queue {
+	return &MemoryQueue{
+		queues: map[string][]*Job{},
+		notify: make(chan struct{}, 1),
+	}
+}
+
+// Close doesn't do anything for memory queue.
+func (q *MemoryQueue) Close() error {
+	return nil
+}
+
+// Enqueue pushes the job into its queue so that it can be processed immediately.
+func (q *MemoryQueue) Enqueue(job *Job) error {
+	q.mu.Lock()
+	q.queues[job.Queue] = append(q.queues[job.Queue], copyJob(job))
+	q.mu.Unlock()
+
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// Schedule stores the job so that it will only be pushed into its queue at the specified time.
+func (q *MemoryQueue) Schedule(job *Job, at time.Time) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.scheduled = append(q.scheduled, &scheduledJob{copyJob(job), at})
+	sort.SliceStable(q.scheduled, func(i, j int) bool {
+		return q.scheduled[i].at.Before(q.scheduled[j].at)
+	})
+
+	return nil
+}
+
+// Dequeue pops the next job from the queues in the given priority order. It waits up to the timeout for a job to
+// arrive and returns nil if there is none.
+func (q *MemoryQueue) Dequeue(queues []string, timeout time.Duration) (*Job, error) {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		if job := q.pop(queues); job != nil {
+			return job, nil
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return nil, nil
+		}
+
+		select {
+		case <-q.notify:
+		case <-time.After(remaining):
+		}
+	}
+}
+
+// Ack doesn't do anything for memory queue as the dequeued jobs are lost with the process anyway.
+func (q *MemoryQueue) Ack(job *Job) error {
+	return nil
+}
+
+// Promote pushes the scheduled jobs that are due by now into their queues.
+func (q *MemoryQueue) Promote(now time.Time) error {
+	q.mu.Lock()
+
+	idx := 0
+	for ; idx < len(q.scheduled) && !q.scheduled[idx].at.After(now); idx++ {
+		job := q.scheduled[idx].job
+		q.queues[job.Queue] = append(q.queues[job.Queue], job)
+	}
+	q.scheduled = q.scheduled[idx:]
+	q.mu.Unlock()
+
+	if idx > 0 {
+		select {
+		case q.notify <- struct{}{}:
+		default:
+		}
+	}
+
+	return nil
+}
+
+// Kill moves the job into the dead letter set which won't be retried anymore.
+func (q *MemoryQueue) Kill(job *Job) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.dead = append(q.dead, copyJob(job))
+	return nil
+}
+
+// Pending returns the jobs that are waiting to be processed in the queue.
+func (q *MemoryQueue) Pending(queue string) ([]*Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	jobs := []*Job{}
+	for _, job := range q.queues[queue] {
+		jobs = append(jobs, copyJob(job))
+	}
+
+	return jobs, nil
+}
+
+// Scheduled returns the jobs that are waiting for their scheduled time.
+func (q *MemoryQueue) Scheduled() ([]*Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	jobs := []*Job{}
+	for _, scheduled := range q.scheduled {
+		jobs = append(jobs, copyJob(scheduled.job))
+	}
+
+	return jobs, nil
+}
+
+// Dead returns the jobs in the dead letter set.
+func (q *MemoryQueue) Dead() ([]*Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	jobs := []*Job{}
+	for _, job := range q.dead {
+		jobs = append(jobs, copyJob(job))
+	}
+
+	return jobs, nil
+}
+
+func (q *MemoryQueue) pop(queues []string) *Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, queue := range queues {
+		if len(q.queues[queue]) > 0 {
+			job := q.queues[queue][0]
+			q.queues[queue] = q.queues[queue][1:]
+
+			return job
+		}
+	}
+
+	return nil
+}
+
+func copyJob(job *Job) *Job {
+	c := *job
+	return &c
+}
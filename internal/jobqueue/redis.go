@@ -0,0 +1,299 @@
+package jobqueue
+
+import (
+	"math"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+type (
+	// RedisQueue stores the jobs in the redis backend.
+	RedisQueue struct {
+		Pool              *redis.Pool
+		keyPrefix         string
+		maxDead           int
+		processingTimeout time.Duration
+	}
+)
+
+var (
+	promoteBatchSize = 100
+)
+
+// NewRedisQueueWithPool initializes a RedisQueue instance with a redis pool and the processing timeout that a dequeued
+// job has to be acked within. For more details on the redis pool configuration, please refer to
+// http://godoc.org/github.com/gomodule/redigo/redis#Pool.
+func NewRedisQueueWithPool(pool *redis.Pool, processingTimeout time.Duration) Queue {
+	return &RedisQueue{
+		Pool:              pool,
+		keyPrefix:         "jobs:",
+		maxDead:           10000,
+		processingTimeout: processingTimeout,
+	}
+}
+
+// Close closes the underlying *redis.Pool
+func (q *RedisQueue) Close() error {
+	return q.Pool.Close()
+}
+
+// Enqueue pushes the job into its queue so that it can be processed immediately.
+func (q *RedisQueue) Enqueue(job *Job) error {
+	b, err := marshalJob(job)
+	if err != nil {
+		return err
+	}
+
+	conn := q.Pool.Get()
+	defer conn.Close()
+
+	_, err = conn.Do("LPUSH", q.queueKey(job.Queue), b)
+	return err
+}
+
+// Schedule stores the job so that it will only be pushed into its queue at the specified time.
+func (q *RedisQueue) Schedule(job *Job, at time.Time) error {
+	b, err := marshalJob(job)
+	if err != nil {
+		return err
+	}
+
+	conn := q.Pool.Get()
+	defer conn.Close()
+
+	_, err = conn.Do("ZADD", q.keyPrefix+"scheduled", at.Unix(), b)
+	return err
+}
+
+// Dequeue moves the next job from the queues in the given priority order into the processing list where it stays until
+// it is acked, or is pushed back into its queue by Promote if it isn't acked within the processing timeout, e.g. the
+// worker died mid-job. It waits up to the timeout for a job to arrive and returns nil if there is none.
+func (q *RedisQueue) Dequeue(queues []string, timeout time.Duration) (*Job, error) {
+	conn := q.Pool.Get()
+	defer conn.Close()
+
+	for _, queue := range queues {
+		data, err := redis.Bytes(conn.Do("RPOPLPUSH", q.queueKey(queue), q.processingKey(queue)))
+		if err == redis.ErrNil {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		return q.lease(conn, data)
+	}
+
+	// BRPOPLPUSH with 0 timeout blocks forever and can only wait on a single queue, so only the highest priority queue
+	// is waited on while the others are checked again on the next dequeue.
+	if timeout <= 0 || len(queues) < 1 {
+		return nil, nil
+	}
+
+	data, err := redis.Bytes(
+		conn.Do("BRPOPLPUSH", q.queueKey(queues[0]), q.processingKey(queues[0]), int(math.Ceil(timeout.Seconds()))),
+	)
+	if err == redis.ErrNil {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return q.lease(conn, data)
+}
+
+// Ack removes the dequeued job from the processing list after it is processed, retried or killed so that it won't be
+// pushed back into its queue.
+func (q *RedisQueue) Ack(job *Job) error {
+	conn := q.Pool.Get()
+	defer conn.Close()
+
+	if _, err := conn.Do("LREM", q.processingKey(job.Queue), 1, job.raw); err != nil {
+		return err
+	}
+
+	_, err := conn.Do("ZREM", q.leasesKey(), job.raw)
+	return err
+}
+
+// Promote pushes the scheduled jobs that are due by now and the processing jobs that aren't acked within the
+// processing timeout back into their queues.
+func (q *RedisQueue) Promote(now time.Time) error {
+	conn := q.Pool.Get()
+	defer conn.Close()
+
+	if err := q.promote(conn, q.keyPrefix+"scheduled", now, q.pushScheduled); err != nil {
+		return err
+	}
+
+	return q.promote(conn, q.leasesKey(), now, q.pushExpired)
+}
+
+// Kill moves the job into the dead letter set which won't be retried anymore.
+func (q *RedisQueue) Kill(job *Job) error {
+	b, err := marshalJob(job)
+	if err != nil {
+		return err
+	}
+
+	conn := q.Pool.Get()
+	defer conn.Close()
+
+	if _, err := conn.Do("ZADD", q.keyPrefix+"dead", job.FailedAt.Unix(), b); err != nil {
+		return err
+	}
+
+	_, err = conn.Do("ZREMRANGEBYRANK", q.keyPrefix+"dead", 0, -(q.maxDead + 1))
+	return err
+}
+
+// Pending returns the jobs that are waiting to be processed in the queue.
+func (q *RedisQueue) Pending(queue string) ([]*Job, error) {
+	conn := q.Pool.Get()
+	defer conn.Close()
+
+	members, err := redis.ByteSlices(conn.Do("LRANGE", q.queueKey(queue), 0, -1))
+	if err != nil {
+		return nil, err
+	}
+
+	// The list is pushed from the left and popped from the right, reverse it to return the processing order.
+	for i, j := 0, len(members)-1; i < j; i, j = i+1, j-1 {
+		members[i], members[j] = members[j], members[i]
+	}
+
+	return unmarshalJobs(members)
+}
+
+// Scheduled returns the jobs that are waiting for their scheduled time.
+func (q *RedisQueue) Scheduled() ([]*Job, error) {
+	return q.sortedSet(q.keyPrefix + "scheduled")
+}
+
+// Dead returns the jobs in the dead letter set.
+func (q *RedisQueue) Dead() ([]*Job, error) {
+	return q.sortedSet(q.keyPrefix + "dead")
+}
+
+// KeyPrefix returns the prefix for the redis key.
+func (q *RedisQueue) KeyPrefix() string {
+	return q.keyPrefix
+}
+
+// SetKeyPrefix sets the prefix for the redis key.
+func (q *RedisQueue) SetKeyPrefix(p string) {
+	q.keyPrefix = p
+}
+
+func (q *RedisQueue) queueKey(queue string) string {
+	return q.keyPrefix + "queue:" + queue
+}
+
+func (q *RedisQueue) processingKey(queue string) string {
+	return q.keyPrefix + "processing:" + queue
+}
+
+func (q *RedisQueue) leasesKey() string {
+	return q.keyPrefix + "leases"
+}
+
+// lease records when the job that is just moved into the processing list should be pushed back into its queue if it
+// isn't acked by then. Note that the job stays in the processing list without a lease if the worker dies in between.
+func (q *RedisQueue) lease(conn redis.Conn, data []byte) (*Job, error) {
+	job, err := unmarshalJob(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := conn.Do("ZADD", q.leasesKey(), time.Now().Add(q.processingTimeout).Unix(), data); err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// promote pops the members that are due by now from the sorted set in batches and pushes them with the push func.
+func (q *RedisQueue) promote(conn redis.Conn, key string, now time.Time, push func(redis.Conn, []byte) error) error {
+	for {
+		members, err := redis.ByteSlices(conn.Do("ZRANGEBYSCORE", key, "-inf", now.Unix(), "LIMIT", 0, promoteBatchSize))
+		if err != nil {
+			return err
+		}
+
+		for _, member := range members {
+			// Only the process that manages to remove the member from the sorted set gets to push it into the queue.
+			removed, err := redis.Int(conn.Do("ZREM", key, member))
+			if err != nil {
+				return err
+			}
+
+			if removed == 0 {
+				continue
+			}
+
+			if err := push(conn, member); err != nil {
+				return err
+			}
+		}
+
+		if len(members) < promoteBatchSize {
+			return nil
+		}
+	}
+}
+
+func (q *RedisQueue) pushScheduled(conn redis.Conn, member []byte) error {
+	job, err := unmarshalJob(member)
+	if err != nil {
+		return err
+	}
+
+	_, err = conn.Do("LPUSH", q.queueKey(job.Queue), member)
+	return err
+}
+
+// pushExpired pushes the expired job back to the processing end of its queue unless it is already acked.
+func (q *RedisQueue) pushExpired(conn redis.Conn, member []byte) error {
+	job, err := unmarshalJob(member)
+	if err != nil {
+		return err
+	}
+
+	removed, err := redis.Int(conn.Do("LREM", q.processingKey(job.Queue), 1, member))
+	if err != nil || removed == 0 {
+		return err
+	}
+
+	_, err = conn.Do("RPUSH", q.queueKey(job.Queue), member)
+	return err
+}
+
+func (q *RedisQueue) sortedSet(key string) ([]*Job, error) {
+	conn := q.Pool.Get()
+	defer conn.Close()
+
+	members, err := redis.ByteSlices(conn.Do("ZRANGE", key, 0, -1))
+	if err != nil {
+		return nil, err
+	}
+
+	return unmarshalJobs(members)
+}
+
+func unmarshalJobs(members [][]byte) ([]*Job, error) {
+	jobs := []*Job{}
+	for _, member := range members {
+		job, err := unmarshalJob(member)
+		if err != nil {
+			return nil, err
+		}
+
+		jobs = append(jobs, job)
+	}
+
+	return jobs, nil
+}
@@ -35,3 +35,22 @@ func (s *CookieStore) KeyPrefix() string {
 // SetKeyPrefix doesn't do anything for cookie store.
 func (s *CookieStore) SetKeyPrefix(p string) {
 }
+
+// Serializer returns nil for cookie store as the session values are encoded by the securecookie.
+func (s *CookieStore) Serializer() SessionSerializer {
+	return nil
+}
+
+// SetSerializer doesn't do anything for cookie store.
+func (s *CookieStore) SetSerializer(serializer SessionSerializer) {
+}
+
+// Close doesn't do anything for cookie store.
+func (s *CookieStore) Close() error {
+	return nil
+}
+
+// Stats returns the empty statistics for cookie store as it has no connection pool.
+func (s *CookieStore) Stats() Stats {
+	return Stats{}
+}
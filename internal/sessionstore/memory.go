@@ -0,0 +1,280 @@
+package sessionstore
+
+import (
+	"container/list"
+	"encoding/base32"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	ginsessions "github.com/gin-contrib/sessions"
+	"github.com/gorilla/securecookie"
+	gorsessions "github.com/gorilla/sessions"
+)
+
+// memorySweepInterval is the minimum interval between the sweeps of the expired sessions.
+const memorySweepInterval = time.Minute
+
+type (
+	// MemoryStore stores sessions in the process memory which evicts the least recently used session when it reaches
+	// the maximum entries, and sweeps the expired sessions at most once every minute when a session is saved. It is
+	// meant for the tests and the single-node development as the sessions are neither shared across the processes nor
+	// persisted across the restarts.
+	MemoryStore struct {
+		Codecs        []securecookie.Codec
+		CookieOptions *gorsessions.Options // default configuration
+		DefaultMaxAge int                  // default TTL for a MaxAge == 0 session
+		entries       map[string]*list.Element
+		keyPrefix     string
+		lastSweep     time.Time
+		lru           *list.List
+		maxEntries    int
+		mu            *sync.Mutex
+		now           func() time.Time
+		serializer    SessionSerializer
+	}
+
+	memoryEntry struct {
+		data      []byte
+		expiresAt time.Time
+		key       string
+	}
+)
+
+// NewMemoryStore initializes a MemoryStore instance which keeps at most maxEntries sessions. The number of sessions
+// is unlimited if maxEntries is 0.
+func NewMemoryStore(maxEntries int, keyPairs ...[]byte) Store {
+	return &MemoryStore{
+		Codecs: securecookie.CodecsFromPairs(keyPairs...),
+		CookieOptions: &gorsessions.Options{
+			Path:   "/",
+			MaxAge: defaultCookieMaxAge,
+		},
+		DefaultMaxAge: 60 * 20, // 20 minutes seems like a reasonable default
+		entries:       map[string]*list.Element{},
+		keyPrefix:     "session:",
+		lru:           list.New(),
+		maxEntries:    maxEntries,
+		mu:            &sync.Mutex{},
+		now:           time.Now,
+		serializer:    GobSerializer{},
+	}
+}
+
+// Close removes all the sessions from the memory.
+func (s *MemoryStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries = map[string]*list.Element{}
+	s.lru.Init()
+	return nil
+}
+
+// Stats returns the number of sessions in the memory as the active count, including the expired ones that are not
+// removed yet.
+func (s *MemoryStore) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return Stats{ActiveCount: s.lru.Len()}
+}
+
+// Get returns a session for the given name after adding it to the registry.
+func (s *MemoryStore) Get(r *http.Request, name string) (*gorsessions.Session, error) {
+	return gorsessions.GetRegistry(r).Get(s, name)
+}
+
+// New returns a session for the given name without adding it to the registry.
+func (s *MemoryStore) New(r *http.Request, name string) (*gorsessions.Session, error) {
+	var (
+		err error
+		ok  bool
+	)
+	session := gorsessions.NewSession(s, name)
+	// make a copy
+	options := *s.CookieOptions
+	session.Options = &options
+	session.IsNew = true
+	if c, errCookie := r.Cookie(name); errCookie == nil {
+		err = securecookie.DecodeMulti(name, c.Value, &session.ID, s.Codecs...)
+		if err == nil {
+			ok, err = s.load(session, s.key(r, session))
+			session.IsNew = !(err == nil && ok) // not new if no error and data available
+		}
+	}
+
+	return session, err
+}
+
+// Options defines how the session cookie should be configured.
+func (s *MemoryStore) Options(options ginsessions.Options) {
+	s.CookieOptions = &gorsessions.Options{
+		Path:     options.Path,
+		Domain:   options.Domain,
+		MaxAge:   options.MaxAge,
+		SameSite: options.SameSite,
+		Secure:   options.Secure,
+		HttpOnly: options.HttpOnly,
+	}
+}
+
+// Save adds a single session to the response.
+func (s *MemoryStore) Save(r *http.Request, w http.ResponseWriter, session *gorsessions.Session) error {
+	// Marked for deletion.
+	if session.Options.MaxAge <= 0 {
+		s.delete(s.key(r, session))
+		http.SetCookie(w, gorsessions.NewCookie(session.Name(), "", session.Options))
+	} else {
+		// Build an alphanumeric key for the memory store.
+		if session.ID == "" {
+			session.ID = strings.TrimRight(base32.StdEncoding.EncodeToString(securecookie.GenerateRandomKey(32)), "=")
+		}
+
+		if err := s.save(session, s.key(r, session)); err != nil {
+			return err
+		}
+
+		encoded, err := securecookie.EncodeMulti(session.Name(), session.ID, s.Codecs...)
+		if err != nil {
+			return err
+		}
+
+		http.SetCookie(w, gorsessions.NewCookie(session.Name(), encoded, session.Options))
+	}
+
+	return nil
+}
+
+// KeyPrefix returns the prefix for the session key.
+func (s *MemoryStore) KeyPrefix() string {
+	return s.keyPrefix
+}
+
+// SetKeyPrefix sets the prefix for the session key.
+func (s *MemoryStore) SetKeyPrefix(p string) {
+	s.keyPrefix = p
+}
+
+// Serializer returns the serializer for the session values.
+func (s *MemoryStore) Serializer() SessionSerializer {
+	return s.serializer
+}
+
+// SetSerializer sets the serializer for the session values. The sessions are stored as the serialized payload so that
+// the values are copied like the other stores, and the ones written by another serializer are migrated when read.
+func (s *MemoryStore) SetSerializer(serializer SessionSerializer) {
+	s.serializer = serializer
+}
+
+// SetClock sets the func that returns the current time for the expiry which is useful for testing.
+func (s *MemoryStore) SetClock(now func() time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.now = now
+}
+
+// key returns the session key with the request's key prefix.
+func (s *MemoryStore) key(r *http.Request, session *gorsessions.Session) string {
+	return RequestKeyPrefix(r, s.keyPrefix) + session.ID
+}
+
+// save stores the session in the memory with the expiry time, sweeps the expired sessions if the sweep interval has
+// passed and evicts the least recently used session if the maximum entries is still exceeded.
+func (s *MemoryStore) save(session *gorsessions.Session, key string) error {
+	b, err := Encode(s.serializer, session)
+	if err != nil {
+		return err
+	}
+
+	age := session.Options.MaxAge
+	if age == 0 {
+		age = s.DefaultMaxAge
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.lastSweep) >= memorySweepInterval {
+		s.sweep(now)
+	}
+
+	entry := &memoryEntry{data: b, expiresAt: now.Add(time.Duration(age) * time.Second), key: key}
+	if elem, ok := s.entries[key]; ok {
+		elem.Value = entry
+		s.lru.MoveToFront(elem)
+		return nil
+	}
+
+	s.entries[key] = s.lru.PushFront(entry)
+	for s.maxEntries > 0 && s.lru.Len() > s.maxEntries {
+		s.removeElement(s.lru.Back())
+	}
+
+	return nil
+}
+
+// load reads the session that isn't expired from the memory and returns true if there is a session data in memory.
+func (s *MemoryStore) load(session *gorsessions.Session, key string) (bool, error) {
+	s.mu.Lock()
+	elem, ok := s.entries[key]
+	if !ok {
+		s.mu.Unlock()
+		return false, nil
+	}
+
+	entry := elem.Value.(*memoryEntry)
+	if !s.now().Before(entry.expiresAt) {
+		s.removeElement(elem)
+		s.mu.Unlock()
+		return false, nil
+	}
+
+	s.lru.MoveToFront(elem)
+	s.mu.Unlock()
+
+	migrate, err := Decode(entry.data, session, s.serializer)
+	if err != nil {
+		return true, err
+	}
+
+	// The migration failure is ignored as the session is still readable and it will be migrated on the next read.
+	if migrate {
+		_ = s.save(session, key)
+	}
+
+	return true, nil
+}
+
+// delete removes the session from the memory if MaxAge<0
+func (s *MemoryStore) delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if elem, ok := s.entries[key]; ok {
+		s.removeElement(elem)
+	}
+}
+
+// sweep removes the expired sessions so that the ones which are never read again don't stay in the memory until they
+// are evicted.
+func (s *MemoryStore) sweep(now time.Time) {
+	for elem := s.lru.Back(); elem != nil; {
+		prev := elem.Prev()
+		if !now.Before(elem.Value.(*memoryEntry).expiresAt) {
+			s.removeElement(elem)
+		}
+
+		elem = prev
+	}
+
+	s.lastSweep = now
+}
+
+func (s *MemoryStore) removeElement(elem *list.Element) {
+	s.lru.Remove(elem)
+	delete(s.entries, elem.Value.(*memoryEntry).key)
+}
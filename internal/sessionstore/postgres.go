@@ -0,0 +1,320 @@
+package sessionstore
+
+import (
+	"encoding/base32"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	ginsessions "github.com/gin-contrib/sessions"
+	"github.com/go-pg/pg/v9"
+	"github.com/gorilla/securecookie"
+	gorsessions "github.com/gorilla/sessions"
+)
+
+// PostgresStore stores sessions in the postgres table.
+type PostgresStore struct {
+	DB            func() *pg.DB // returns nil if the database isn't connected yet
+	Codecs        []securecookie.Codec
+	CookieOptions *gorsessions.Options // default configuration
+	DefaultMaxAge int                  // default TTL for a MaxAge == 0 session
+	keyPrefix     string
+	mu            *sync.Mutex
+	quit          chan struct{}
+	serializer    SessionSerializer
+	table         string
+	tableCreated  bool
+	wg            *sync.WaitGroup
+}
+
+// NewPostgresStore initializes a PostgresStore instance which creates the table on its first query if it doesn't
+// exist, and deletes the expired sessions every cleanup interval in the background until it is closed. The cleanup is
+// disabled if the interval is 0. The database is resolved on every query, so that the store can be initialized before
+// the database is connected.
+func NewPostgresStore(db func() *pg.DB, table string, cleanupInterval time.Duration, keyPairs ...[]byte) Store {
+	ps := &PostgresStore{
+		DB:     db,
+		Codecs: securecookie.CodecsFromPairs(keyPairs...),
+		CookieOptions: &gorsessions.Options{
+			Path:   "/",
+			MaxAge: defaultCookieMaxAge,
+		},
+		DefaultMaxAge: 60 * 20, // 20 minutes seems like a reasonable default
+		keyPrefix:     "session:",
+		mu:            &sync.Mutex{},
+		quit:          make(chan struct{}),
+		serializer:    GobSerializer{},
+		table:         table,
+		wg:            &sync.WaitGroup{},
+	}
+
+	if cleanupInterval > 0 {
+		ps.wg.Add(1)
+		go ps.cleanup(cleanupInterval)
+	}
+
+	return ps
+}
+
+// Close stops the expired sessions cleanup. The underlying *pg.DB is not closed as it is owned by the DB manager.
+func (s *PostgresStore) Close() error {
+	select {
+	case <-s.quit:
+	default:
+		close(s.quit)
+	}
+
+	s.wg.Wait()
+	return nil
+}
+
+// Stats returns the underlying *pg.DB pool statistics.
+func (s *PostgresStore) Stats() Stats {
+	db := s.DB()
+	if db == nil {
+		return Stats{}
+	}
+
+	stats := db.PoolStats()
+
+	return Stats{
+		ActiveCount: int(stats.TotalConns),
+		IdleCount:   int(stats.IdleConns),
+	}
+}
+
+// DeleteExpired deletes the expired sessions which is also done by the cleanup in the background.
+func (s *PostgresStore) DeleteExpired() error {
+	db, err := s.db()
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec("DELETE FROM ? WHERE expires_at <= NOW()", pg.Ident(s.table))
+
+	return s.checkTable(err)
+}
+
+// Get returns a session for the given name after adding it to the registry.
+func (s *PostgresStore) Get(r *http.Request, name string) (*gorsessions.Session, error) {
+	return gorsessions.GetRegistry(r).Get(s, name)
+}
+
+// New returns a session for the given name without adding it to the registry.
+func (s *PostgresStore) New(r *http.Request, name string) (*gorsessions.Session, error) {
+	var (
+		err error
+		ok  bool
+	)
+	session := gorsessions.NewSession(s, name)
+	// make a copy
+	options := *s.CookieOptions
+	session.Options = &options
+	session.IsNew = true
+	if c, errCookie := r.Cookie(name); errCookie == nil {
+		err = securecookie.DecodeMulti(name, c.Value, &session.ID, s.Codecs...)
+		if err == nil {
+			ok, err = s.load(session, s.key(r, session))
+			session.IsNew = !(err == nil && ok) // not new if no error and data available
+		}
+	}
+
+	return session, err
+}
+
+// Options defines how the session cookie should be configured.
+func (s *PostgresStore) Options(options ginsessions.Options) {
+	s.CookieOptions = &gorsessions.Options{
+		Path:     options.Path,
+		Domain:   options.Domain,
+		MaxAge:   options.MaxAge,
+		SameSite: options.SameSite,
+		Secure:   options.Secure,
+		HttpOnly: options.HttpOnly,
+	}
+}
+
+// Save adds a single session to the response.
+func (s *PostgresStore) Save(r *http.Request, w http.ResponseWriter, session *gorsessions.Session) error {
+	// Marked for deletion.
+	if session.Options.MaxAge <= 0 {
+		if err := s.delete(s.key(r, session)); err != nil {
+			return err
+		}
+
+		http.SetCookie(w, gorsessions.NewCookie(session.Name(), "", session.Options))
+	} else {
+		// Build an alphanumeric key for the postgres table.
+		if session.ID == "" {
+			session.ID = strings.TrimRight(base32.StdEncoding.EncodeToString(securecookie.GenerateRandomKey(32)), "=")
+		}
+
+		if err := s.save(session, s.key(r, session)); err != nil {
+			return err
+		}
+
+		encoded, err := securecookie.EncodeMulti(session.Name(), session.ID, s.Codecs...)
+		if err != nil {
+			return err
+		}
+
+		http.SetCookie(w, gorsessions.NewCookie(session.Name(), encoded, session.Options))
+	}
+
+	return nil
+}
+
+// KeyPrefix returns the prefix for the session key.
+func (s *PostgresStore) KeyPrefix() string {
+	return s.keyPrefix
+}
+
+// SetKeyPrefix sets the prefix for the session key.
+func (s *PostgresStore) SetKeyPrefix(p string) {
+	s.keyPrefix = p
+}
+
+// Serializer returns the serializer for the session values.
+func (s *PostgresStore) Serializer() SessionSerializer {
+	return s.serializer
+}
+
+// SetSerializer sets the serializer for the session values. The sessions that are written by another serializer are
+// still readable and migrated to this serializer when they are read.
+func (s *PostgresStore) SetSerializer(serializer SessionSerializer) {
+	s.serializer = serializer
+}
+
+// db returns the connected database after creating the sessions table with the index on the expiry time if they
+// don't exist. The table creation is retried on the next query if it fails.
+func (s *PostgresStore) db() (*pg.DB, error) {
+	db := s.DB()
+	if db == nil {
+		return nil, errors.New("database for the postgres session store is not connected")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.tableCreated {
+		return db, nil
+	}
+
+	index := s.table
+	if idx := strings.LastIndex(index, "."); idx > -1 {
+		index = index[idx+1:]
+	}
+
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS ? (key TEXT PRIMARY KEY, data BYTEA NOT NULL, expires_at TIMESTAMPTZ NOT NULL);
+CREATE INDEX IF NOT EXISTS ? ON ? (expires_at);`, pg.Ident(s.table), pg.Ident(index+"_expires_at_idx"), pg.Ident(s.table))
+	if err != nil {
+		return nil, err
+	}
+
+	s.tableCreated = true
+	return db, nil
+}
+
+// checkTable marks the table as not created if the err is the undefined_table error, e.g. its creation is rolled back
+// or it is dropped, so that it is created again on the next query.
+func (s *PostgresStore) checkTable(err error) error {
+	if pgErr, ok := err.(pg.Error); ok && pgErr.Field('C') == "42P01" {
+		s.mu.Lock()
+		s.tableCreated = false
+		s.mu.Unlock()
+	}
+
+	return err
+}
+
+// cleanup deletes the expired sessions every interval until the store is closed.
+func (s *PostgresStore) cleanup(interval time.Duration) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.quit:
+			return
+		case <-ticker.C:
+			// The failure is retried on the next tick.
+			_ = s.DeleteExpired()
+		}
+	}
+}
+
+// key returns the session key with the request's key prefix.
+func (s *PostgresStore) key(r *http.Request, session *gorsessions.Session) string {
+	return RequestKeyPrefix(r, s.keyPrefix) + session.ID
+}
+
+// save stores the session in postgres with the expiry time.
+func (s *PostgresStore) save(session *gorsessions.Session, key string) error {
+	b, err := Encode(s.serializer, session)
+	if err != nil {
+		return err
+	}
+
+	age := session.Options.MaxAge
+	if age == 0 {
+		age = s.DefaultMaxAge
+	}
+
+	db, err := s.db()
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`INSERT INTO ? (key, data, expires_at) VALUES (?, ?, NOW() + ? * INTERVAL '1 second')
+ON CONFLICT (key) DO UPDATE SET data = EXCLUDED.data, expires_at = EXCLUDED.expires_at`,
+		pg.Ident(s.table), key, b, age)
+
+	return s.checkTable(err)
+}
+
+// load reads the session that isn't expired from postgres and returns true if there is a session data in DB.
+func (s *PostgresStore) load(session *gorsessions.Session, key string) (bool, error) {
+	db, err := s.db()
+	if err != nil {
+		return false, err
+	}
+
+	var b []byte
+	_, err = db.QueryOne(pg.Scan(&b), "SELECT data FROM ? WHERE key = ? AND expires_at > NOW()",
+		pg.Ident(s.table), key)
+	if err == pg.ErrNoRows {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, s.checkTable(err)
+	}
+
+	migrate, err := Decode(b, session, s.serializer)
+	if err != nil {
+		return true, err
+	}
+
+	// The migration failure is ignored as the session is still readable and it will be migrated on the next read.
+	if migrate {
+		_ = s.save(session, key)
+	}
+
+	return true, nil
+}
+
+// delete removes the session from postgres if MaxAge<0
+func (s *PostgresStore) delete(key string) error {
+	db, err := s.db()
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec("DELETE FROM ? WHERE key = ?", pg.Ident(s.table), key)
+
+	return s.checkTable(err)
+}
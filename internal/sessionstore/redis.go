@@ -1,9 +1,7 @@
 package sessionstore
 
 import (
-	"bytes"
 	"encoding/base32"
-	"encoding/gob"
 	"errors"
 	"net/http"
 	"strings"
@@ -15,12 +13,6 @@ import (
 )
 
 type (
-	// SessionSerializer provides an interface hook for alternative serializers.
-	SessionSerializer interface {
-		Deserialize(d []byte, ss *gorsessions.Session) error
-		Serialize(ss *gorsessions.Session) ([]byte, error)
-	}
-
 	// RedisStore stores sessions in the redis backend.
 	RedisStore struct {
 		Pool          *redis.Pool
@@ -31,32 +23,12 @@ type (
 		keyPrefix     string
 		serializer    SessionSerializer
 	}
-
-	// GobSerializer uses gob package to encode the session map.
-	GobSerializer struct{}
 )
 
 var (
 	defaultCookieMaxAge = 86400 * 14
 )
 
-// Serialize using gob
-func (s GobSerializer) Serialize(ss *gorsessions.Session) ([]byte, error) {
-	buf := new(bytes.Buffer)
-	enc := gob.NewEncoder(buf)
-	err := enc.Encode(ss.Values)
-	if err == nil {
-		return buf.Bytes(), nil
-	}
-	return nil, err
-}
-
-// Deserialize uses gob package to decode the session map.
-func (s GobSerializer) Deserialize(d []byte, ss *gorsessions.Session) error {
-	dec := gob.NewDecoder(bytes.NewBuffer(d))
-	return dec.Decode(&ss.Values)
-}
-
 // NewRedisStoreWithPool initializes a RedisStore instance with a redis pool. For more details on the redis pool
 // configuration, please refer to http://godoc.org/github.com/gomodule/redigo/redis#Pool.
 func NewRedisStoreWithPool(pool *redis.Pool, keyPairs ...[]byte) (Store, error) {
@@ -85,6 +57,16 @@ func (s *RedisStore) Close() error {
 	return s.Pool.Close()
 }
 
+// Stats returns the underlying *redis.Pool statistics.
+func (s *RedisStore) Stats() Stats {
+	stats := s.Pool.Stats()
+
+	return Stats{
+		ActiveCount: stats.ActiveCount,
+		IdleCount:   stats.IdleCount,
+	}
+}
+
 // Get returns a session for the given name after adding it to the registry.
 func (s *RedisStore) Get(r *http.Request, name string) (*gorsessions.Session, error) {
 	return gorsessions.GetRegistry(r).Get(s, name)
@@ -104,7 +86,7 @@ func (s *RedisStore) New(r *http.Request, name string) (*gorsessions.Session, er
 	if c, errCookie := r.Cookie(name); errCookie == nil {
 		err = securecookie.DecodeMulti(name, c.Value, &session.ID, s.Codecs...)
 		if err == nil {
-			ok, err = s.load(session)
+			ok, err = s.load(session, s.key(r, session))
 			session.IsNew = !(err == nil && ok) // not new if no error and data available
 		}
 	}
@@ -128,7 +110,7 @@ func (s *RedisStore) Options(options ginsessions.Options) {
 func (s *RedisStore) Save(r *http.Request, w http.ResponseWriter, session *gorsessions.Session) error {
 	// Marked for deletion.
 	if session.Options.MaxAge <= 0 {
-		if err := s.delete(session); err != nil {
+		if err := s.delete(s.key(r, session)); err != nil {
 			return err
 		}
 
@@ -139,7 +121,7 @@ func (s *RedisStore) Save(r *http.Request, w http.ResponseWriter, session *gorse
 			session.ID = strings.TrimRight(base32.StdEncoding.EncodeToString(securecookie.GenerateRandomKey(32)), "=")
 		}
 
-		if err := s.save(session); err != nil {
+		if err := s.save(session, s.key(r, session)); err != nil {
 			return err
 		}
 
@@ -164,6 +146,17 @@ func (s *RedisStore) SetKeyPrefix(p string) {
 	s.keyPrefix = p
 }
 
+// Serializer returns the serializer for the session values.
+func (s *RedisStore) Serializer() SessionSerializer {
+	return s.serializer
+}
+
+// SetSerializer sets the serializer for the session values. The sessions that are written by another serializer are
+// still readable and migrated to this serializer when they are read.
+func (s *RedisStore) SetSerializer(serializer SessionSerializer) {
+	s.serializer = serializer
+}
+
 // ping does an internal ping against a server to check if it is alive.
 func (s *RedisStore) ping() (bool, error) {
 	conn := s.Pool.Get()
@@ -177,9 +170,14 @@ func (s *RedisStore) ping() (bool, error) {
 	return (data == "PONG"), nil
 }
 
+// key returns the session key with the request's key prefix.
+func (s *RedisStore) key(r *http.Request, session *gorsessions.Session) string {
+	return RequestKeyPrefix(r, s.keyPrefix) + session.ID
+}
+
 // save stores the session in redis.
-func (s *RedisStore) save(session *gorsessions.Session) error {
-	b, err := s.serializer.Serialize(session)
+func (s *RedisStore) save(session *gorsessions.Session, key string) error {
+	b, err := Encode(s.serializer, session)
 	if err != nil {
 		return err
 	}
@@ -199,7 +197,7 @@ func (s *RedisStore) save(session *gorsessions.Session) error {
 		age = s.DefaultMaxAge
 	}
 
-	_, err = conn.Do("SETEX", s.keyPrefix+session.ID, age, b)
+	_, err = conn.Do("SETEX", key, age, b)
 	if err != nil {
 		return err
 	}
@@ -208,14 +206,14 @@ func (s *RedisStore) save(session *gorsessions.Session) error {
 }
 
 // load reads the session from redis and returns true if there is a sessoin data in DB.
-func (s *RedisStore) load(session *gorsessions.Session) (bool, error) {
+func (s *RedisStore) load(session *gorsessions.Session, key string) (bool, error) {
 	conn := s.Pool.Get()
 	defer conn.Close()
 	if err := conn.Err(); err != nil {
 		return false, err
 	}
 
-	data, err := conn.Do("GET", s.keyPrefix+session.ID)
+	data, err := conn.Do("GET", key)
 	if err != nil {
 		return false, err
 	}
@@ -229,14 +227,24 @@ func (s *RedisStore) load(session *gorsessions.Session) (bool, error) {
 		return false, err
 	}
 
-	return true, s.serializer.Deserialize(b, session)
+	migrate, err := Decode(b, session, s.serializer)
+	if err != nil {
+		return true, err
+	}
+
+	// The migration failure is ignored as the session is still readable and it will be migrated on the next read.
+	if migrate {
+		_ = s.save(session, key)
+	}
+
+	return true, nil
 }
 
 // delete removes keys from redis if MaxAge<0
-func (s *RedisStore) delete(session *gorsessions.Session) error {
+func (s *RedisStore) delete(key string) error {
 	conn := s.Pool.Get()
 	defer conn.Close()
-	if _, err := conn.Do("DEL", s.keyPrefix+session.ID); err != nil {
+	if _, err := conn.Do("DEL", key); err != nil {
 		return err
 	}
 
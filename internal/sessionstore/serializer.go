@@ -0,0 +1,215 @@
+package sessionstore
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	gorsessions "github.com/gorilla/sessions"
+	"github.com/vmihailenco/msgpack/v4"
+)
+
+type (
+	// SessionSerializer provides an interface hook for alternative serializers.
+	SessionSerializer interface {
+		Deserialize(d []byte, ss *gorsessions.Session) error
+		Serialize(ss *gorsessions.Session) ([]byte, error)
+	}
+
+	// NamedSessionSerializer is a SessionSerializer which opts in the `appy:<version>:<name>:` payload header so that
+	// its payload can still be read after switching to another serializer. The name must be registered with
+	// RegisterSerializer.
+	NamedSessionSerializer interface {
+		SessionSerializer
+		Name() string
+	}
+
+	// GobSerializer uses gob package to encode the session map.
+	GobSerializer struct{}
+
+	// JSONSerializer uses json package to encode the session map which only supports the string keys. Note that the
+	// numbers are decoded as float64.
+	JSONSerializer struct{}
+
+	// MsgpackSerializer uses msgpack package to encode the session map.
+	MsgpackSerializer struct{}
+)
+
+const (
+	payloadHeaderPrefix = "appy:"
+	payloadVersion      = "1"
+)
+
+var (
+	serializers = map[string]SessionSerializer{
+		"gob":     GobSerializer{},
+		"json":    JSONSerializer{},
+		"msgpack": MsgpackSerializer{},
+	}
+	serializersMu = &sync.RWMutex{}
+)
+
+// RegisterSerializer registers the serializer with the name so that it can be picked by NewSerializer and its payload
+// can be read by Decode.
+func RegisterSerializer(name string, serializer SessionSerializer) {
+	serializersMu.Lock()
+	defer serializersMu.Unlock()
+
+	serializers[name] = serializer
+}
+
+// NewSerializer returns the serializer with the name, i.e. "gob", "json", "msgpack" or the one which is registered
+// with RegisterSerializer.
+func NewSerializer(name string) (SessionSerializer, error) {
+	serializersMu.RLock()
+	defer serializersMu.RUnlock()
+
+	if serializer, ok := serializers[name]; ok {
+		return serializer, nil
+	}
+
+	return nil, fmt.Errorf("session serializer '%s' is not supported", name)
+}
+
+// Encode serializes the session values. The payload of NamedSessionSerializer is prefixed with the
+// `appy:<version>:<name>:` header, e.g. `appy:1:msgpack:...`, whereas the gob and json payloads are kept as is so
+// that they can be read by the other services.
+func Encode(serializer SessionSerializer, ss *gorsessions.Session) ([]byte, error) {
+	b, err := serializer.Serialize(ss)
+	if err != nil {
+		return nil, err
+	}
+
+	named, ok := serializer.(NamedSessionSerializer)
+	if !ok {
+		return b, nil
+	}
+
+	header := payloadHeaderPrefix + payloadVersion + ":" + named.Name() + ":"
+	return append([]byte(header), b...), nil
+}
+
+// Decode deserializes the payload with the serializer in its header. The payload without header is a gob or json
+// payload which is detected by its content, or the payload of the custom serializer without name which is always
+// read by the serializer. It returns true if the payload isn't written by the serializer so that it should be
+// migrated.
+func Decode(d []byte, ss *gorsessions.Session, serializer SessionSerializer) (bool, error) {
+	current := serializerName(serializer)
+
+	if !bytes.HasPrefix(d, []byte(payloadHeaderPrefix)) {
+		if current == "" {
+			return false, serializer.Deserialize(d, ss)
+		}
+
+		name := "gob"
+		if len(d) > 0 && d[0] == '{' && json.Valid(d) {
+			name = "json"
+		}
+
+		return decodeWith(name, current, serializer, d, ss)
+	}
+
+	parts := strings.SplitN(string(d[len(payloadHeaderPrefix):]), ":", 3)
+	if len(parts) < 3 {
+		return false, errors.New("session payload header is invalid")
+	}
+
+	version, name, payload := parts[0], parts[1], parts[2]
+	if version != payloadVersion {
+		return false, fmt.Errorf("session payload version '%s' is not supported", version)
+	}
+
+	return decodeWith(name, current, serializer, []byte(payload), ss)
+}
+
+func decodeWith(name, current string, serializer SessionSerializer, d []byte, ss *gorsessions.Session) (bool, error) {
+	if name == current {
+		return false, serializer.Deserialize(d, ss)
+	}
+
+	previous, err := NewSerializer(name)
+	if err != nil {
+		return false, err
+	}
+
+	return true, previous.Deserialize(d, ss)
+}
+
+// serializerName returns the name of the built-in serializer or NamedSessionSerializer, otherwise an empty string.
+func serializerName(serializer SessionSerializer) string {
+	switch s := serializer.(type) {
+	case NamedSessionSerializer:
+		return s.Name()
+	case GobSerializer:
+		return "gob"
+	case JSONSerializer:
+		return "json"
+	}
+
+	return ""
+}
+
+// Serialize using gob
+func (s GobSerializer) Serialize(ss *gorsessions.Session) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	enc := gob.NewEncoder(buf)
+	err := enc.Encode(ss.Values)
+	if err == nil {
+		return buf.Bytes(), nil
+	}
+	return nil, err
+}
+
+// Deserialize uses gob package to decode the session map.
+func (s GobSerializer) Deserialize(d []byte, ss *gorsessions.Session) error {
+	dec := gob.NewDecoder(bytes.NewBuffer(d))
+	return dec.Decode(&ss.Values)
+}
+
+// Serialize uses json package to encode the session map.
+func (s JSONSerializer) Serialize(ss *gorsessions.Session) ([]byte, error) {
+	values := map[string]interface{}{}
+	for key, val := range ss.Values {
+		k, ok := key.(string)
+		if !ok {
+			return nil, fmt.Errorf("session key '%v' must be a string to be serialized with json", key)
+		}
+
+		values[k] = val
+	}
+
+	return json.Marshal(values)
+}
+
+// Deserialize uses json package to decode the session map.
+func (s JSONSerializer) Deserialize(d []byte, ss *gorsessions.Session) error {
+	values := map[string]interface{}{}
+	if err := json.Unmarshal(d, &values); err != nil {
+		return err
+	}
+
+	for key, val := range values {
+		ss.Values[key] = val
+	}
+
+	return nil
+}
+
+// Serialize uses msgpack package to encode the session map.
+func (s MsgpackSerializer) Serialize(ss *gorsessions.Session) ([]byte, error) {
+	return msgpack.Marshal(ss.Values)
+}
+
+// Deserialize uses msgpack package to decode the session map.
+func (s MsgpackSerializer) Deserialize(d []byte, ss *gorsessions.Session) error {
+	return msgpack.Unmarshal(d, &ss.Values)
+}
+
+// Name returns "msgpack".
+func (s MsgpackSerializer) Name() string {
+	return "msgpack"
+}
@@ -1,6 +1,9 @@
 package sessionstore
 
 import (
+	"context"
+	"net/http"
+
 	ginsessions "github.com/gin-contrib/sessions"
 )
 
@@ -14,5 +17,43 @@ type (
 
 		// SetKeyPrefix sets the prefix for the store key, not available for CookieStore.
 		SetKeyPrefix(p string)
+
+		// Serializer returns the serializer for the session values, not available for CookieStore.
+		Serializer() SessionSerializer
+
+		// SetSerializer sets the serializer for the session values, not available for CookieStore.
+		SetSerializer(serializer SessionSerializer)
+
+		// Close closes the store's connection pool, not available for CookieStore.
+		Close() error
+
+		// Stats returns the store's connection pool statistics, not available for CookieStore.
+		Stats() Stats
 	}
+
+	// Stats contains the store's connection pool statistics.
+	Stats struct {
+		// ActiveCount is the number of connections in the pool, including the idle connections and connections in use.
+		ActiveCount int
+
+		// IdleCount is the number of idle connections in the pool.
+		IdleCount int
+	}
+
+	keyPrefixCtxKey struct{}
 )
+
+// SetRequestKeyPrefix sets the key prefix for the sessions of the request which takes precedence over the store's key
+// prefix, so that the store which is shared across the requests isn't mutated by a single request.
+func SetRequestKeyPrefix(r *http.Request, p string) {
+	*r = *r.WithContext(context.WithValue(r.Context(), keyPrefixCtxKey{}, p))
+}
+
+// RequestKeyPrefix returns the key prefix for the sessions of the request, or the store's key prefix if it isn't set.
+func RequestKeyPrefix(r *http.Request, storeKeyPrefix string) string {
+	if p, ok := r.Context().Value(keyPrefixCtxKey{}).(string); ok {
+		return p
+	}
+
+	return storeKeyPrefix
+}
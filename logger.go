@@ -4,7 +4,10 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"regexp"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"go.uber.org/zap"
@@ -15,7 +18,17 @@ type (
 	// Logger provides the logging functionality.
 	Logger struct {
 		*zap.SugaredLogger
-		dbLogging bool
+		dbLogging            bool
+		dbSlowQueryThreshold time.Duration
+	}
+
+	// dbQueryStats aggregates the queries that run with the request's context.
+	dbQueryStats struct {
+		count     int
+		duration  time.Duration
+		mu        *sync.Mutex
+		requestID string
+		shapes    map[string]int
 	}
 )
 
@@ -23,6 +36,20 @@ const (
 	dbQueryComment = "/* appy framework */"
 )
 
+var (
+	dbQueryStatsCtxKey = ContextKey("dbQueryStats")
+
+	dbQueryShapeReplacers = []struct {
+		regex       *regexp.Regexp
+		replacement string
+	}{
+		{regexp.MustCompile(`'(?:[^']|'')*'`), "?"},
+		{regexp.MustCompile(`\b\d+(?:\.\d+)?\b`), "?"},
+		{regexp.MustCompile(`\(\?(?:\s*,\s*\?)*\)`), "(?)"},
+	}
+	dbQueryReplacer = strings.NewReplacer("\n", "", ",\n", ", ", "\t", "")
+)
+
 // NewLogger initializes Logger instance.
 func NewLogger() *Logger {
 	c := newLoggerConfig()
@@ -57,13 +84,37 @@ func (l Logger) BeforeQuery(c context.Context, e *DBQueryEvent) (context.Context
 	return c, nil
 }
 
-// AfterQuery is a hook after a go-pg's DB query.
+// AfterQuery is a hook after a go-pg's DB query which measures the query duration and adds it to the request's query
+// stats if the query runs with the request's context, e.g. `db.ModelContext(c, &users)`. The query that takes longer
+// than the slow query threshold is logged at warn level with the request ID even if the DB logging is disabled, and
+// its parameters are replaced with `?` so that the sensitive values don't end up in the production logs.
 func (l Logger) AfterQuery(c context.Context, e *DBQueryEvent) error {
 	query, err := e.FormattedQuery()
+	if strings.Contains(query, dbQueryComment) {
+		return err
+	}
+
+	duration := time.Since(e.StartTime)
+	query = dbQueryReplacer.Replace(query)
+
+	requestID := ""
+	if stats := dbQueryStatsFromContext(c); stats != nil {
+		stats.add(query, duration)
+		requestID = stats.requestID
+	}
+
+	if l.dbSlowQueryThreshold > 0 && duration >= l.dbSlowQueryThreshold {
+		if requestID != "" {
+			l.SugaredLogger.Warnf("[SQL] %s slow query in %s: %s", requestID, duration, dbQueryShape(query))
+		} else {
+			l.SugaredLogger.Warnf("[SQL] slow query in %s: %s", duration, dbQueryShape(query))
+		}
+
+		return err
+	}
 
-	if !strings.Contains(query, dbQueryComment) && l.dbLogging {
-		replacer := strings.NewReplacer("\n", "", ",\n", ", ", "\t", "")
-		l.SugaredLogger.Infof("[SQL] %s in %s", replacer.Replace(query), time.Since(e.StartTime))
+	if l.dbLogging {
+		l.SugaredLogger.Infof("[SQL] %s in %s", query, duration)
 	}
 
 	return err
@@ -79,6 +130,99 @@ func (l *Logger) SetDBLogging(enabled bool) {
 	l.dbLogging = enabled
 }
 
+// DBSlowQueryThreshold returns the duration which the query that takes longer than is logged as a slow query.
+func (l Logger) DBSlowQueryThreshold() time.Duration {
+	return l.dbSlowQueryThreshold
+}
+
+// SetDBSlowQueryThreshold can be used to set the duration which the query that takes longer than is logged as a slow
+// query. The slow query logging is disabled if it is 0.
+func (l *Logger) SetDBSlowQueryThreshold(threshold time.Duration) {
+	l.dbSlowQueryThreshold = threshold
+}
+
+func newDBQueryStats(requestID string) *dbQueryStats {
+	return &dbQueryStats{
+		mu:        &sync.Mutex{},
+		requestID: requestID,
+		shapes:    map[string]int{},
+	}
+}
+
+func dbQueryStatsFromContext(c context.Context) *dbQueryStats {
+	if c == nil {
+		return nil
+	}
+
+	if stats, ok := c.Value(dbQueryStatsCtxKey).(*dbQueryStats); ok {
+		return stats
+	}
+
+	// The gin's context looks up the value that is set with the string key.
+	if stats, ok := c.Value(dbQueryStatsCtxKey.String()).(*dbQueryStats); ok {
+		return stats
+	}
+
+	return nil
+}
+
+func (s *dbQueryStats) add(query string, duration time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.count++
+	s.duration += duration
+
+	// The query shapes are only tracked for the N+1 queries detection in the debug build.
+	if IsDebugBuild() {
+		s.shapes[dbQueryShape(query)]++
+	}
+}
+
+// totals returns the number of queries and their total duration.
+func (s *dbQueryStats) totals() (int, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.count, s.duration
+}
+
+// repeatedShapes returns the query shapes which run at least the threshold times with their counts, sorted by the
+// shape.
+func (s *dbQueryStats) repeatedShapes(threshold int) ([]string, []int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	shapes := []string{}
+	if threshold < 2 {
+		return shapes, []int{}
+	}
+
+	for shape, count := range s.shapes {
+		if count >= threshold {
+			shapes = append(shapes, shape)
+		}
+	}
+	sort.Strings(shapes)
+
+	counts := make([]int, len(shapes))
+	for i, shape := range shapes {
+		counts[i] = s.shapes[shape]
+	}
+
+	return shapes, counts
+}
+
+// dbQueryShape replaces the query's literals with `?` so that the queries that only differ in their parameters have
+// the same shape.
+func dbQueryShape(query string) string {
+	for _, replacer := range dbQueryShapeReplacers {
+		query = replacer.regex.ReplaceAllString(query, replacer.replacement)
+	}
+
+	return query
+}
+
 func newLoggerConfig() zap.Config {
 	c := zap.NewDevelopmentConfig()
 	c.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
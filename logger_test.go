@@ -3,6 +3,7 @@ package appy_test
 import (
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/appist/appy"
 )
@@ -43,6 +44,13 @@ func (s *LoggerSuite) TestSetDBLogging() {
 	s.Equal(false, logger.DBLogging())
 }
 
+func (s *LoggerSuite) TestSetDBSlowQueryThreshold() {
+	logger := appy.NewLogger()
+	s.Equal(time.Duration(0), logger.DBSlowQueryThreshold())
+	logger.SetDBSlowQueryThreshold(200 * time.Millisecond)
+	s.Equal(200*time.Millisecond, logger.DBSlowQueryThreshold())
+}
+
 func TestLoggerSuite(t *testing.T) {
 	appy.RunTestSuite(t, new(LoggerSuite))
 }
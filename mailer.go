@@ -2,56 +2,120 @@ package appy
 
 import (
 	"bytes"
-	"crypto/tls"
+	"context"
+	"encoding/gob"
+	"fmt"
 	"html/template"
+	"io"
+	"io/ioutil"
+	"mime"
 	"net/http"
-	"net/smtp"
 	"net/textproto"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/CloudyKit/jet"
 	"github.com/jordan-wright/email"
 )
 
 type (
-	// Mailer provides the capability to parse/render email template and send it out via SMTP protocol.
+	// Mailer provides the capability to parse/render email template and send it out via the MailTransport.
 	Mailer struct {
-		config     *Config
-		deliveries []Mail
-		i18n       *I18n
-		previews   map[string]Mail
-		server     *Server
-		smtpAddr   string
-		smtpAuth   smtp.Auth
-		viewEngine *ViewEngine
+		config       *Config
+		errors       []error
+		i18n         *I18n
+		interceptors []MailInterceptor
+		logger       *Logger
+		observers    []MailObserver
+		previews     map[string]Mail
+		server       *Server
+		transport    MailTransport
+		viewEngine   *ViewEngine
+		worker       *Worker
 	}
 
-	// Mail defines the email headers/body/attachments.
+	// Mail defines the email headers/body/attachments. Note that the interceptors/observers are not serialized, hence
+	// they are not available with DeliverLater. The TemplateData is gob encoded by DeliverLater to keep its types, so
+	// its custom types must be registered with `gob.Register`.
 	Mail struct {
-		From, Sender, Subject, Template, Locale        string
-		To, ReplyTo, Bcc, Cc, Attachments, ReadReceipt []string
-		Headers                                        textproto.MIMEHeader
-		TemplateData                                   interface{}
+		From, Sender, Subject, Template, Locale string
+		To, ReplyTo, Bcc, Cc, ReadReceipt       []string
+		Attachments                             []MailAttachment
+		Headers                                 textproto.MIMEHeader
+		TemplateData                            interface{}
+		Interceptors                            []MailInterceptor `json:"-"`
+		Observers                               []MailObserver    `json:"-"`
 	}
+
+	// MailAttachment defines the email attachment whose content is read from Content, Reader or the file at Path in
+	// order. Name defaults to the Path's base name and ContentType defaults to the type guessed from the Name's
+	// extension. An inline attachment can be referenced in the HTML template with `cid:<Name>`, i.e.
+	// `<img src="cid:logo.png">`.
+	MailAttachment struct {
+		Name        string
+		ContentType string
+		Path        string
+		Content     []byte
+		Reader      io.Reader `json:"-"`
+		Inline      bool
+	}
+
+	// MailInterceptor is invoked with the composed email right before it is sent which can mutate the email, i.e.
+	// rewriting the recipients or adding the tracking headers. Returning ErrMailCanceled skips sending the email
+	// silently whereas returning other errors aborts the delivery with the error.
+	MailInterceptor func(mail Mail, email *email.Email) error
+
+	// MailObserver is invoked after the email is sent with the transport's result, i.e. for auditing.
+	MailObserver func(mail Mail, email *email.Email, err error)
+
+	// mailPayload is the mail without the interceptors/observers which gob can't encode.
+	mailPayload struct {
+		From, Sender, Subject, Template, Locale string
+		To, ReplyTo, Bcc, Cc, ReadReceipt       []string
+		Attachments                             []MailAttachment
+		Headers                                 textproto.MIMEHeader
+		TemplateData                            interface{}
+	}
+)
+
+func init() {
+	// The common TemplateData types that are kept by DeliverLater.
+	gob.Register(H{})
+	gob.Register(map[string]interface{}{})
+	gob.Register([]interface{}{})
+	gob.Register(time.Time{})
+}
+
+const (
+	// MailerDeliverJob is the job name that is used by DeliverLater to deliver the email in the background.
+	MailerDeliverJob = "appy.mailer.deliver"
 )
 
 // NewMailer initializes Mailer instance.
-func NewMailer(asset *Asset, config *Config, i18n *I18n, logger *Logger, server *Server, viewFuncs map[string]interface{}) *Mailer {
+func NewMailer(asset *Asset, config *Config, i18n *I18n, logger *Logger, server *Server, worker *Worker, viewFuncs map[string]interface{}) *Mailer {
 	ve := NewViewEngine(asset, config, logger)
 	ve.SetGlobalFuncs(viewFuncs)
 
 	mailer := &Mailer{
-		config:   config,
-		i18n:     i18n,
-		previews: map[string]Mail{},
-		server:   server,
-		smtpAddr: config.MailerSMTPAddr,
-		smtpAuth: smtp.PlainAuth(
-			config.MailerSMTPPlainAuthIdentity,
-			config.MailerSMTPPlainAuthUsername,
-			config.MailerSMTPPlainAuthPassword,
-			config.MailerSMTPPlainAuthHost,
-		),
+		config:     config,
+		i18n:       i18n,
+		logger:     logger,
+		previews:   map[string]Mail{},
+		server:     server,
 		viewEngine: ve,
+		worker:     worker,
+	}
+
+	transport, err := newMailTransport(config)
+	if err != nil {
+		mailer.errors = append(mailer.errors, err)
+	}
+	mailer.transport = transport
+
+	if worker != nil {
+		worker.Register(MailerDeliverJob, mailer.deliverJob)
 	}
 
 	return mailer
@@ -59,12 +123,50 @@ func NewMailer(asset *Asset, config *Config, i18n *I18n, logger *Logger, server
 
 // AddPreview add the mail HTML/text template preview.
 func (m *Mailer) AddPreview(mail Mail) {
+	attachments, err := bufferMailAttachments(mail.Attachments)
+	if err != nil {
+		m.logger.Error(err)
+	}
+
+	mail.Attachments = attachments
 	m.previews[mail.Template] = mail
 }
 
-// Deliveries returns the Mail array which is used for unit test with APPY_ENV=test.
+// Deliveries returns the Mail array which is used for unit test with APPY_ENV=test. It is only available with the
+// MemoryMailTransport.
 func (m *Mailer) Deliveries() []Mail {
-	return m.deliveries
+	if transport, ok := m.transport.(*MemoryMailTransport); ok {
+		return transport.Deliveries()
+	}
+
+	return nil
+}
+
+// Emails returns the composed emails after the interceptors ran which is used for unit test with APPY_ENV=test. It is
+// only available with the MemoryMailTransport.
+func (m *Mailer) Emails() []*email.Email {
+	if transport, ok := m.transport.(*MemoryMailTransport); ok {
+		return transport.Emails()
+	}
+
+	return nil
+}
+
+// AddInterceptor adds the interceptor that is invoked for every email before it is sent. The global interceptors are
+// invoked before the mail's interceptors.
+func (m *Mailer) AddInterceptor(interceptor MailInterceptor) {
+	m.interceptors = append(m.interceptors, interceptor)
+}
+
+// AddObserver adds the observer that is invoked for every email after it is sent. The global observers are invoked
+// before the mail's observers.
+func (m *Mailer) AddObserver(observer MailObserver) {
+	m.observers = append(m.observers, observer)
+}
+
+// Errors returns the mailer's initialization errors, i.e. unsupported MAILER_TRANSPORT.
+func (m *Mailer) Errors() []error {
+	return m.errors
 }
 
 // Previews returns all the templates preview.
@@ -72,11 +174,21 @@ func (m *Mailer) Previews() map[string]Mail {
 	return m.previews
 }
 
-// Deliver sends the email via SMTP protocol without TLS.
+// SetTransport sets the transport that delivers the emails.
+func (m *Mailer) SetTransport(transport MailTransport) {
+	m.transport = transport
+}
+
+// Transport returns the transport that delivers the emails which is selected by MAILER_TRANSPORT, or the
+// MemoryMailTransport with APPY_ENV=test.
+func (m *Mailer) Transport() MailTransport {
+	return m.transport
+}
+
+// Deliver composes the email and sends it out via the transport immediately.
 func (m *Mailer) Deliver(mail Mail) error {
-	if m.config.AppyEnv == "test" {
-		m.deliveries = append(m.deliveries, mail)
-		return nil
+	if m.transport == nil {
+		return ErrMissingMailerTransport
 	}
 
 	email, err := m.composeEmail(mail)
@@ -84,7 +196,108 @@ func (m *Mailer) Deliver(mail Mail) error {
 		return err
 	}
 
-	return email.SendWithTLS(m.smtpAddr, m.smtpAuth, &tls.Config{})
+	for _, interceptor := range append(append([]MailInterceptor{}, m.interceptors...), mail.Interceptors...) {
+		if err := interceptor(mail, email); err != nil {
+			if err == ErrMailCanceled {
+				return nil
+			}
+
+			return err
+		}
+	}
+
+	err = m.transport.Send(mail, email)
+	for _, observer := range append(append([]MailObserver{}, m.observers...), mail.Observers...) {
+		observer(mail, email, err)
+	}
+
+	return err
+}
+
+// DeliverLater serializes the mail with gob into the worker's queue so that it is delivered in the background with
+// retry and backoff. The attachments' Reader or file at Path are read before the mail is enqueued, and it returns an
+// error if the TemplateData has a type that isn't registered with `gob.Register`. With APPY_ENV=test, the mail is
+// captured in Deliveries() once the worker drains its queues.
+func (m *Mailer) DeliverLater(mail Mail, opts ...JobOption) (*Job, error) {
+	if m.worker == nil {
+		return nil, ErrMissingMailerWorker
+	}
+
+	attachments, err := bufferMailAttachments(mail.Attachments)
+	if err != nil {
+		return nil, err
+	}
+
+	// The files are read now as they might be removed or not exist on the worker by the time the mail is delivered.
+	for idx, attachment := range attachments {
+		if attachment.Content == nil {
+			if attachments[idx].Content, err = attachment.Bytes(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	payload := mailPayload{
+		From:         mail.From,
+		Sender:       mail.Sender,
+		Subject:      mail.Subject,
+		Template:     mail.Template,
+		Locale:       mail.Locale,
+		To:           mail.To,
+		ReplyTo:      mail.ReplyTo,
+		Bcc:          mail.Bcc,
+		Cc:           mail.Cc,
+		ReadReceipt:  mail.ReadReceipt,
+		Attachments:  attachments,
+		Headers:      mail.Headers,
+		TemplateData: mail.TemplateData,
+	}
+	if err := gob.NewEncoder(&buf).Encode(payload); err != nil {
+		return nil, err
+	}
+
+	return m.worker.Enqueue(MailerDeliverJob, buf.Bytes(), opts...)
+}
+
+func (m *Mailer) deliverJob(ctx context.Context, job *Job) error {
+	mail, err := decodeMailJob(job)
+	if err != nil {
+		m.logger.Errorf("[MAILER] unable to decode the mail in job %s: %s", job.ID, err)
+		return err
+	}
+
+	if err := m.Deliver(mail); err != nil {
+		m.logger.Errorf("[MAILER] failed to deliver '%s' to %v in job %s (retry: %d/%d): %s", mail.Template, mail.To,
+			job.ID, job.Retry, job.MaxRetry, err)
+		return err
+	}
+
+	return nil
+}
+
+// RewriteMailRecipients returns the interceptor that sends the emails to the specified recipients instead which is
+// useful for staging environment. The original recipients are kept in X-Original-To/Cc headers, but not the Bcc
+// recipients which must not be disclosed to the other recipients.
+func RewriteMailRecipients(recipients ...string) MailInterceptor {
+	return func(mail Mail, email *email.Email) error {
+		originals := map[string][]string{
+			"X-Original-To": email.To,
+			"X-Original-Cc": email.Cc,
+		}
+
+		for key, addrs := range originals {
+			if len(addrs) > 0 {
+				email.Headers.Set(key, strings.Join(addrs, ", "))
+			}
+		}
+
+		email.To = recipients
+		email.Cc = nil
+		email.Bcc = nil
+
+		return nil
+	}
 }
 
 func (m *Mailer) composeEmail(mail Mail) (*email.Email, error) {
@@ -108,8 +321,9 @@ func (m *Mailer) composeEmail(mail Mail) (*email.Email, error) {
 		email.Subject = subject
 	}
 
-	if mail.Headers == nil {
-		email.Headers = textproto.MIMEHeader{}
+	email.Headers = textproto.MIMEHeader{}
+	for key, values := range mail.Headers {
+		email.Headers[key] = append([]string{}, values...)
 	}
 
 	html, err := m.content(mail.Locale, mail.Template+".html", mail.TemplateData)
@@ -124,16 +338,131 @@ func (m *Mailer) composeEmail(mail Mail) (*email.Email, error) {
 	}
 	email.Text = text
 
-	if mail.Attachments != nil {
-		for _, attachment := range mail.Attachments {
-			_, err := email.AttachFile(attachment)
+	for _, attachment := range mail.Attachments {
+		if err := attachment.attach(email); err != nil {
+			return nil, err
+		}
+	}
+
+	return email, nil
+}
+
+// MailAttachmentsFromPaths returns the attachments for the files at the paths which eases the migration from the
+// file paths that Mail.Attachments used to be.
+func MailAttachmentsFromPaths(paths ...string) []MailAttachment {
+	attachments := []MailAttachment{}
+	for _, path := range paths {
+		attachments = append(attachments, MailAttachment{Path: path})
+	}
+
+	return attachments
+}
+
+// Bytes returns the attachment's content which is read from Content, Reader or the file at Path in order.
+func (a MailAttachment) Bytes() ([]byte, error) {
+	switch {
+	case a.Content != nil:
+		return a.Content, nil
+	case a.Reader != nil:
+		return ioutil.ReadAll(a.Reader)
+	}
+
+	return ioutil.ReadFile(a.Path)
+}
+
+func (a MailAttachment) filename() string {
+	if a.Name != "" {
+		return a.Name
+	}
+
+	return filepath.Base(a.Path)
+}
+
+func (a MailAttachment) contentType() string {
+	if a.ContentType != "" {
+		return a.ContentType
+	}
+
+	return mime.TypeByExtension(filepath.Ext(a.filename()))
+}
+
+func (a MailAttachment) attach(email *email.Email) error {
+	content, err := a.Bytes()
+	if err != nil {
+		return err
+	}
+
+	attachment, err := email.Attach(bytes.NewReader(content), a.filename(), a.contentType())
+	if err != nil {
+		return err
+	}
+
+	if a.Inline {
+		attachment.Header.Set("Content-Disposition", fmt.Sprintf("inline;\r\n filename=\"%s\"", a.filename()))
+	}
+
+	return nil
+}
+
+// decodeMailJob decodes the gob encoded mail that is enqueued by DeliverLater.
+func decodeMailJob(job *Job) (Mail, error) {
+	var (
+		data    []byte
+		payload mailPayload
+	)
+
+	if err := job.Decode(&data); err != nil {
+		return Mail{}, err
+	}
+
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&payload); err != nil {
+		return Mail{}, err
+	}
+
+	return Mail{
+		From:         payload.From,
+		Sender:       payload.Sender,
+		Subject:      payload.Subject,
+		Template:     payload.Template,
+		Locale:       payload.Locale,
+		To:           payload.To,
+		ReplyTo:      payload.ReplyTo,
+		Bcc:          payload.Bcc,
+		Cc:           payload.Cc,
+		ReadReceipt:  payload.ReadReceipt,
+		Attachments:  payload.Attachments,
+		Headers:      payload.Headers,
+		TemplateData: payload.TemplateData,
+	}, nil
+}
+
+// bufferMailAttachments reads the attachments' Reader into Content so that they can be serialized or read repeatedly.
+// It also fills in the default Name/ContentType.
+func bufferMailAttachments(attachments []MailAttachment) ([]MailAttachment, error) {
+	if attachments == nil {
+		return nil, nil
+	}
+
+	buffered := make([]MailAttachment, len(attachments))
+	for idx, attachment := range attachments {
+		if attachment.Content == nil && attachment.Reader != nil {
+			content, err := attachment.Bytes()
 			if err != nil {
 				return nil, err
 			}
+
+			attachment.Content = content
 		}
+
+		// The reader is either buffered or unused as the content takes precedence.
+		attachment.Reader = nil
+
+		attachment.Name = attachment.filename()
+		attachment.ContentType = attachment.contentType()
+		buffered[idx] = attachment
 	}
 
-	return email, nil
+	return buffered, nil
 }
 
 func (m *Mailer) content(locale, name string, obj interface{}) ([]byte, error) {
@@ -227,6 +556,12 @@ func (m *Mailer) previewTpl() string {
 												{{range $idx, $val := .mail.Bcc}}{{if $idx}}, {{end}}{{$val}}{{end}}
 											</td>
 										</tr>
+										<tr>
+											<th class="pr-4" scope="row">Attachments</th>
+											<td>
+												{{range $idx, $val := .mail.Attachments}}{{if $idx}}, {{end}}<a href="{{$.baseURL}}/attachment?name={{$.name}}&file={{$val.Name}}" target="_blank">{{$val.Name}}</a>{{if $val.Inline}} (inline){{end}}{{end}}
+											</td>
+										</tr>
 									</tbody>
 								</table>
 							</div>
@@ -472,7 +807,7 @@ func (m *Mailer) SetupPreview() {
 				panic(err)
 			}
 
-			content = email.HTML
+			content = m.previewInlineAttachments(preview, email.HTML)
 		case "txt":
 			contentType = "text/plain"
 			email, err := m.composeEmail(preview)
@@ -487,4 +822,51 @@ func (m *Mailer) SetupPreview() {
 		c.Writer.Header().Del(http.CanonicalHeaderKey("x-frame-options"))
 		c.Data(http.StatusOK, contentType, content)
 	})
+
+	// Serve the preview attachment.
+	m.server.GET(m.config.MailerPreviewBaseURL+"/attachment", func(c *Context) {
+		preview, exists := m.Previews()[c.Query("name")]
+		if !exists {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+
+		for _, attachment := range preview.Attachments {
+			if attachment.Name != c.Query("file") {
+				continue
+			}
+
+			content, err := attachment.Bytes()
+			if err != nil {
+				panic(err)
+			}
+
+			disposition := "attachment"
+			if attachment.Inline {
+				disposition = "inline"
+			}
+
+			c.Header("Content-Disposition", fmt.Sprintf(`%s; filename="%s"`, disposition, attachment.Name))
+			c.Data(http.StatusOK, attachment.ContentType, content)
+			return
+		}
+
+		c.AbortWithStatus(http.StatusNotFound)
+	})
+}
+
+// previewInlineAttachments rewrites the `cid:<Name>` references in the HTML to the preview attachment URL so that the
+// inline attachments can be rendered by the browser.
+func (m *Mailer) previewInlineAttachments(preview Mail, html []byte) []byte {
+	for _, attachment := range preview.Attachments {
+		if !attachment.Inline {
+			continue
+		}
+
+		src := fmt.Sprintf("%s/attachment?name=%s&file=%s", m.config.MailerPreviewBaseURL,
+			url.QueryEscape(preview.Template), url.QueryEscape(attachment.Name))
+		html = bytes.ReplaceAll(html, []byte("cid:"+attachment.Name), []byte(src))
+	}
+
+	return html
 }
@@ -1,11 +1,21 @@
 package appy_test
 
 import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"io/ioutil"
 	"net/http"
+	"net/textproto"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/appist/appy"
+	"github.com/jordan-wright/email"
 )
 
 type MailerSuite struct {
@@ -48,7 +58,7 @@ func (s *MailerSuite) SetupTest() {
 }
 
 func (s *MailerSuite) TestNewMailerWithDebugBuild() {
-	mailer := appy.NewMailer(s.asset, s.config, s.i18n, s.logger, s.server, nil)
+	mailer := appy.NewMailer(s.asset, s.config, s.i18n, s.logger, s.server, nil, nil)
 	mailer.SetupPreview()
 
 	mail := s.previewMail
@@ -57,7 +67,7 @@ func (s *MailerSuite) TestNewMailerWithDebugBuild() {
 	mail.TemplateData = appy.H{
 		"username": "cayter",
 	}
-	mail.Attachments = []string{"testdata/mailer/attachments/fake.txt"}
+	mail.Attachments = []appy.MailAttachment{{Path: "testdata/mailer/attachments/fake.txt"}}
 	mailer.AddPreview(mail)
 	s.Error(mailer.Deliver(mail))
 	s.Equal(0, len(mailer.Deliveries()))
@@ -106,20 +116,97 @@ func (s *MailerSuite) TestNewMailerWithDebugBuild() {
 	mail.TemplateData = appy.H{
 		"username": "cayter",
 	}
-	mail.Attachments = []string{"testdata/mailer/attachments/missing.txt"}
+	mail.Attachments = []appy.MailAttachment{{Path: "testdata/mailer/attachments/missing.txt"}}
 	mailer.AddPreview(mail)
 
 	w = s.server.TestHTTPRequest("GET", s.config.MailerPreviewBaseURL+"/preview?locale=en&ext=html&name=mailers/user/verify_account", nil, nil)
 	s.Equal(http.StatusInternalServerError, w.Code)
 }
 
+func (s *MailerSuite) TestPreviewAttachments() {
+	mailer := appy.NewMailer(s.asset, s.config, s.i18n, s.logger, s.server, nil, nil)
+	mailer.SetupPreview()
+
+	mail := s.previewMail
+	mail.Subject = "mailers.user.verifyAccount.subject"
+	mail.Template = "mailers/user/newsletter"
+	mail.TemplateData = appy.H{
+		"username": "cayter",
+	}
+	mail.Attachments = []appy.MailAttachment{
+		{Path: "testdata/mailer/attachments/logo.png", Inline: true},
+		{Name: "report.csv", Content: []byte("id,name\n1,cayter\n")},
+		{Name: "notes.txt", ContentType: "text/plain", Reader: strings.NewReader("hello")},
+	}
+	mailer.AddPreview(mail)
+	s.Equal("logo.png", mailer.Previews()["mailers/user/newsletter"].Attachments[0].Name)
+	s.Equal("image/png", mailer.Previews()["mailers/user/newsletter"].Attachments[0].ContentType)
+	s.Equal([]byte("hello"), mailer.Previews()["mailers/user/newsletter"].Attachments[2].Content)
+
+	w := s.server.TestHTTPRequest("GET", s.config.MailerPreviewBaseURL+"?name=mailers/user/newsletter", nil, nil)
+	s.Equal(http.StatusOK, w.Code)
+	s.Contains(w.Body.String(), `target="_blank">logo.png</a> (inline)`)
+	s.Contains(w.Body.String(), `target="_blank">report.csv</a>`)
+	s.Contains(w.Body.String(), `target="_blank">notes.txt</a>`)
+
+	w = s.server.TestHTTPRequest("GET", s.config.MailerPreviewBaseURL+"/preview?locale=en&ext=html&name=mailers/user/newsletter", nil, nil)
+	s.Equal(http.StatusOK, w.Code)
+	s.Contains(w.Body.String(), `<img src="`+s.config.MailerPreviewBaseURL+`/attachment?name=mailers%2Fuser%2Fnewsletter&file=logo.png" alt="logo">`)
+
+	w = s.server.TestHTTPRequest("GET", s.config.MailerPreviewBaseURL+"/attachment?name=mailers/user/newsletter&file=logo.png", nil, nil)
+	s.Equal(http.StatusOK, w.Code)
+	s.Equal("image/png", w.Header().Get("Content-Type"))
+	s.Equal(`inline; filename="logo.png"`, w.Header().Get("Content-Disposition"))
+
+	w = s.server.TestHTTPRequest("GET", s.config.MailerPreviewBaseURL+"/attachment?name=mailers/user/newsletter&file=notes.txt", nil, nil)
+	s.Equal(http.StatusOK, w.Code)
+	s.Equal(`attachment; filename="notes.txt"`, w.Header().Get("Content-Disposition"))
+	s.Equal("hello", w.Body.String())
+
+	w = s.server.TestHTTPRequest("GET", s.config.MailerPreviewBaseURL+"/attachment?name=mailers/user/newsletter&file=missing.txt", nil, nil)
+	s.Equal(http.StatusNotFound, w.Code)
+
+	w = s.server.TestHTTPRequest("GET", s.config.MailerPreviewBaseURL+"/attachment?name=mailers/user/missing&file=logo.png", nil, nil)
+	s.Equal(http.StatusNotFound, w.Code)
+}
+
+func (s *MailerSuite) TestComposeAttachments() {
+	s.config.AppyEnv = "test"
+	transport := &fakeMailTransport{}
+	mailer := appy.NewMailer(s.asset, s.config, s.i18n, s.logger, s.server, nil, nil)
+	mailer.SetTransport(transport)
+
+	mail := s.previewMail
+	mail.Subject = "mailers.user.verifyAccount.subject"
+	mail.Template = "mailers/user/newsletter"
+	mail.TemplateData = appy.H{
+		"username": "cayter",
+	}
+	mail.Attachments = []appy.MailAttachment{
+		{Path: "testdata/mailer/attachments/logo.png", Inline: true},
+		{Name: "report.pdf", Reader: bytes.NewReader([]byte("%PDF-1.4"))},
+	}
+	s.NoError(mailer.Deliver(mail))
+
+	attachments := transport.emails[0].Attachments
+	s.Equal(2, len(attachments))
+	s.Equal("logo.png", attachments[0].Filename)
+	s.Equal("image/png", attachments[0].Header.Get("Content-Type"))
+	s.Equal("<logo.png>", attachments[0].Header.Get("Content-ID"))
+	s.True(strings.HasPrefix(attachments[0].Header.Get("Content-Disposition"), "inline;"))
+	s.Equal("report.pdf", attachments[1].Filename)
+	s.Equal("application/pdf", attachments[1].Header.Get("Content-Type"))
+	s.Equal([]byte("%PDF-1.4"), attachments[1].Content)
+	s.True(strings.HasPrefix(attachments[1].Header.Get("Content-Disposition"), "attachment;"))
+}
+
 func (s *MailerSuite) TestNewMailerWithReleaseBuild() {
 	appy.Build = appy.ReleaseBuild
 	defer func() {
 		appy.Build = appy.DebugBuild
 	}()
 
-	mailer := appy.NewMailer(s.asset, s.config, s.i18n, s.logger, s.server, nil)
+	mailer := appy.NewMailer(s.asset, s.config, s.i18n, s.logger, s.server, nil, nil)
 	mail := s.previewMail
 	mail.Subject = "mailers.user.verifyAccount.subject"
 	mail.Template = "mailers/user/verify_account"
@@ -143,7 +230,7 @@ func (s *MailerSuite) TestNewMailerWithReleaseBuild() {
 
 func (s *MailerSuite) TestMailerWithTestAppyEnv() {
 	s.config.AppyEnv = "test"
-	mailer := appy.NewMailer(s.asset, s.config, s.i18n, s.logger, s.server, nil)
+	mailer := appy.NewMailer(s.asset, s.config, s.i18n, s.logger, s.server, nil, nil)
 	mail := s.previewMail
 	mail.Subject = "mailers.user.verifyAccount.subject"
 	mail.Template = "mailers/user/verify_account"
@@ -151,8 +238,199 @@ func (s *MailerSuite) TestMailerWithTestAppyEnv() {
 		"username": "cayter",
 	}
 	mailer.AddPreview(mail)
+	mailer.AddInterceptor(appy.RewriteMailRecipients("qa@appist.io"))
 	s.NoError(mailer.Deliver(mail))
 	s.Equal(1, len(mailer.Deliveries()))
+	s.Equal(mail.To, mailer.Deliveries()[0].To)
+	s.Equal(1, len(mailer.Emails()))
+	s.Equal([]string{"qa@appist.io"}, mailer.Emails()[0].To)
+}
+
+func (s *MailerSuite) TestTransport() {
+	s.config.MailerTransport = "pigeon"
+	mailer := appy.NewMailer(s.asset, s.config, s.i18n, s.logger, s.server, nil, nil)
+	mail := s.previewMail
+	mail.Subject = "mailers.user.verifyAccount.subject"
+	mail.Template = "mailers/user/verify_account"
+	s.Nil(mailer.Transport())
+	s.Equal(1, len(mailer.Errors()))
+	s.EqualError(mailer.Errors()[0], "mailer transport 'pigeon' is not supported")
+	s.Equal(appy.ErrMissingMailerTransport, mailer.Deliver(mail))
+
+	transport := appy.NewMemoryMailTransport()
+	mailer.SetTransport(transport)
+	s.Equal(transport, mailer.Transport())
+	s.NoError(mailer.Deliver(mail))
+	s.Equal(1, len(mailer.Deliveries()))
+
+	mailer.SetTransport(nil)
+	s.Equal(appy.ErrMissingMailerTransport, mailer.Deliver(mail))
+}
+
+func (s *MailerSuite) TestDeliverLater() {
+	s.config.AppyEnv = "test"
+	mailer := appy.NewMailer(s.asset, s.config, s.i18n, s.logger, s.server, nil, nil)
+	mail := s.previewMail
+	mail.Subject = "mailers.user.verifyAccount.subject"
+	mail.Template = "mailers/user/verify_account"
+	_, err := mailer.DeliverLater(mail)
+	s.Equal(appy.ErrMissingMailerWorker, err)
+
+	worker := appy.NewWorker(s.config, s.logger)
+	defer worker.Close()
+
+	mailer = appy.NewMailer(s.asset, s.config, s.i18n, s.logger, s.server, worker, nil)
+	mail.TemplateData = appy.H{
+		"username": "cayter",
+	}
+	job, err := mailer.DeliverLater(mail)
+	s.NoError(err)
+	s.Equal(appy.MailerDeliverJob, job.Name)
+	s.Equal(0, len(mailer.Deliveries()))
+
+	s.NoError(worker.Drain(context.Background()))
+	s.Equal(1, len(mailer.Deliveries()))
+
+	mail.Attachments = []appy.MailAttachment{{Name: "notes.txt", Reader: strings.NewReader("hello")}}
+	_, err = mailer.DeliverLater(mail)
+	s.NoError(err)
+	s.NoError(worker.Drain(context.Background()))
+	s.Equal(2, len(mailer.Deliveries()))
+	s.Equal([]byte("hello"), mailer.Deliveries()[1].Attachments[0].Content)
+	s.Equal("text/plain; charset=utf-8", mailer.Deliveries()[1].Attachments[0].ContentType)
+	s.Equal(mail.To, mailer.Deliveries()[0].To)
+	s.Equal(appy.H{"username": "cayter"}, mailer.Deliveries()[0].TemplateData)
+
+	// The TemplateData's types are kept if they are registered with gob.
+	type verifyAccountData map[string]interface{}
+
+	expiresAt := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	mail.Attachments = nil
+	mail.TemplateData = verifyAccountData{"username": "cayter", "expiresAt": expiresAt}
+	_, err = mailer.DeliverLater(mail)
+	s.Contains(err.Error(), "type not registered for interface")
+
+	gob.Register(verifyAccountData{})
+	_, err = mailer.DeliverLater(mail)
+	s.NoError(err)
+	s.NoError(worker.Drain(context.Background()))
+	s.Equal(3, len(mailer.Deliveries()))
+	s.Equal(verifyAccountData{"username": "cayter", "expiresAt": expiresAt}, mailer.Deliveries()[2].TemplateData)
+
+	// The attachment files are read before the mail is enqueued.
+	dir, err := ioutil.TempDir("", "attachments")
+	s.NoError(err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "report.csv")
+	s.NoError(ioutil.WriteFile(path, []byte("id,name\n1,cayter\n"), 0644))
+	mail.Attachments = appy.MailAttachmentsFromPaths(path)
+	_, err = mailer.DeliverLater(mail)
+	s.NoError(err)
+	s.NoError(os.Remove(path))
+	s.NoError(worker.Drain(context.Background()))
+	s.Equal(4, len(mailer.Deliveries()))
+	s.Equal("report.csv", mailer.Deliveries()[3].Attachments[0].Name)
+	s.Equal([]byte("id,name\n1,cayter\n"), mailer.Deliveries()[3].Attachments[0].Content)
+
+	_, err = mailer.DeliverLater(mail)
+	s.True(os.IsNotExist(err))
+}
+
+type fakeMailTransport struct {
+	emails []*email.Email
+	err    error
+}
+
+func (t *fakeMailTransport) Send(mail appy.Mail, email *email.Email) error {
+	t.emails = append(t.emails, email)
+	return t.err
+}
+
+func (s *MailerSuite) TestInterceptorsAndObservers() {
+	s.config.AppyEnv = "test"
+	transport := &fakeMailTransport{}
+	mailer := appy.NewMailer(s.asset, s.config, s.i18n, s.logger, s.server, nil, nil)
+	mailer.SetTransport(transport)
+
+	calls := []string{}
+	mailer.AddInterceptor(appy.RewriteMailRecipients("qa@appist.io"))
+	mailer.AddInterceptor(func(mail appy.Mail, email *email.Email) error {
+		calls = append(calls, "global interceptor")
+		email.Headers.Set("X-Tracking-ID", "123")
+		return nil
+	})
+	mailer.AddObserver(func(mail appy.Mail, email *email.Email, err error) {
+		calls = append(calls, "global observer")
+		s.Nil(err)
+	})
+
+	mail := s.previewMail
+	mail.Subject = "mailers.user.verifyAccount.subject"
+	mail.Template = "mailers/user/verify_account"
+	mail.Headers = textproto.MIMEHeader{"X-Campaign": []string{"welcome"}}
+	mail.Interceptors = []appy.MailInterceptor{
+		func(mail appy.Mail, email *email.Email) error {
+			calls = append(calls, "mail interceptor")
+			return nil
+		},
+	}
+	mail.Observers = []appy.MailObserver{
+		func(mail appy.Mail, email *email.Email, err error) {
+			calls = append(calls, "mail observer")
+		},
+	}
+	s.NoError(mailer.Deliver(mail))
+	s.Equal([]string{"global interceptor", "mail interceptor", "global observer", "mail observer"}, calls)
+	s.Equal(1, len(transport.emails))
+
+	email := transport.emails[0]
+	s.Equal([]string{"qa@appist.io"}, email.To)
+	s.Nil(email.Cc)
+	s.Nil(email.Bcc)
+	s.Equal("jane@appist.io", email.Headers.Get("X-Original-To"))
+	s.Equal("elaine@appist.io, kerry@appist.io", email.Headers.Get("X-Original-Cc"))
+	s.Equal("", email.Headers.Get("X-Original-Bcc"))
+	s.Equal("123", email.Headers.Get("X-Tracking-ID"))
+	s.Equal("welcome", email.Headers.Get("X-Campaign"))
+	s.Nil(mail.Headers["X-Tracking-ID"])
+}
+
+func (s *MailerSuite) TestInterceptorCancelsDelivery() {
+	s.config.AppyEnv = "test"
+	transport := &fakeMailTransport{err: errors.New("smtp is down")}
+	mailer := appy.NewMailer(s.asset, s.config, s.i18n, s.logger, s.server, nil, nil)
+	mailer.SetTransport(transport)
+
+	var observed error
+	mailer.AddObserver(func(mail appy.Mail, email *email.Email, err error) {
+		observed = err
+	})
+
+	mail := s.previewMail
+	mail.Subject = "mailers.user.verifyAccount.subject"
+	mail.Template = "mailers/user/verify_account"
+	s.EqualError(mailer.Deliver(mail), "smtp is down")
+	s.EqualError(observed, "smtp is down")
+	s.Equal(1, len(transport.emails))
+
+	observed = nil
+	mail.Interceptors = []appy.MailInterceptor{
+		func(mail appy.Mail, email *email.Email) error {
+			return appy.ErrMailCanceled
+		},
+	}
+	s.NoError(mailer.Deliver(mail))
+	s.Nil(observed)
+	s.Equal(1, len(transport.emails))
+
+	mail.Interceptors = []appy.MailInterceptor{
+		func(mail appy.Mail, email *email.Email) error {
+			return errors.New("blocked recipient")
+		},
+	}
+	s.EqualError(mailer.Deliver(mail), "blocked recipient")
+	s.Equal(1, len(transport.emails))
 }
 
 func TestMailerSuite(t *testing.T) {
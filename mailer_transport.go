@@ -0,0 +1,354 @@
+package appy
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	netmail "net/mail"
+	"net/smtp"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jordan-wright/email"
+)
+
+type (
+	// MailTransport delivers the composed email to its recipients.
+	MailTransport interface {
+		Send(mail Mail, email *email.Email) error
+	}
+
+	// SMTPMailTransport delivers the email via SMTP protocol with the configurable TLS mode and authentication.
+	SMTPMailTransport struct {
+		addr        string
+		auth        smtp.Auth
+		dialTimeout time.Duration
+		tlsMode     string
+	}
+
+	// SendmailMailTransport delivers the email by piping it into the sendmail binary.
+	SendmailMailTransport struct {
+		path string
+	}
+
+	// FileMailTransport writes the email as `.eml` file into a folder which is useful for development.
+	FileMailTransport struct {
+		path string
+	}
+
+	// MemoryMailTransport keeps the delivered mails and their composed emails in memory which is used for unit test
+	// with APPY_ENV=test.
+	MemoryMailTransport struct {
+		deliveries []Mail
+		emails     []*email.Email
+		mu         *sync.Mutex
+	}
+
+	smtpLoginAuth struct {
+		username, password string
+	}
+)
+
+// NewSMTPMailTransport initializes SMTPMailTransport instance with MAILER_SMTP_TLS_MODE which can be "tls" (implicit
+// TLS), "starttls" or "none", and MAILER_SMTP_AUTH which can be "plain", "login", "cram-md5" or "none". The connection
+// to MAILER_SMTP_ADDR times out after MAILER_SMTP_DIAL_TIMEOUT. The SMTP server is only required to support AUTH when
+// MAILER_SMTP_PLAIN_AUTH_USERNAME or MAILER_SMTP_PLAIN_AUTH_PASSWORD is set.
+func NewSMTPMailTransport(config *Config) (*SMTPMailTransport, error) {
+	switch config.MailerSMTPTLSMode {
+	case "tls", "starttls", "none":
+	default:
+		return nil, fmt.Errorf("mailer SMTP TLS mode '%s' is not supported", config.MailerSMTPTLSMode)
+	}
+
+	host := config.MailerSMTPPlainAuthHost
+	if host == "" {
+		host, _, _ = net.SplitHostPort(config.MailerSMTPAddr)
+	}
+
+	var auth smtp.Auth
+	switch config.MailerSMTPAuth {
+	case "plain":
+		auth = smtp.PlainAuth(
+			config.MailerSMTPPlainAuthIdentity,
+			config.MailerSMTPPlainAuthUsername,
+			config.MailerSMTPPlainAuthPassword,
+			host,
+		)
+	case "login":
+		auth = &smtpLoginAuth{config.MailerSMTPPlainAuthUsername, config.MailerSMTPPlainAuthPassword}
+	case "cram-md5":
+		auth = smtp.CRAMMD5Auth(config.MailerSMTPPlainAuthUsername, config.MailerSMTPPlainAuthPassword)
+	case "none":
+	default:
+		return nil, fmt.Errorf("mailer SMTP auth '%s' is not supported", config.MailerSMTPAuth)
+	}
+
+	if config.MailerSMTPPlainAuthUsername == "" && config.MailerSMTPPlainAuthPassword == "" {
+		auth = nil
+	}
+
+	return &SMTPMailTransport{
+		addr:        config.MailerSMTPAddr,
+		auth:        auth,
+		dialTimeout: config.MailerSMTPDialTimeout,
+		tlsMode:     config.MailerSMTPTLSMode,
+	}, nil
+}
+
+// Send delivers the email via SMTP protocol.
+func (t *SMTPMailTransport) Send(mail Mail, email *email.Email) error {
+	sender, recipients, err := mailEnvelope(email)
+	if err != nil {
+		return err
+	}
+
+	raw, err := email.Bytes()
+	if err != nil {
+		return err
+	}
+
+	host, _, err := net.SplitHostPort(t.addr)
+	if err != nil {
+		return err
+	}
+
+	tlsConfig := &tls.Config{ServerName: host}
+
+	var conn net.Conn
+	dialer := &net.Dialer{Timeout: t.dialTimeout}
+	if t.tlsMode == "tls" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", t.addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", t.addr)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if err := c.Hello("localhost"); err != nil {
+		return err
+	}
+
+	if t.tlsMode == "starttls" {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return errors.New("mailer SMTP server doesn't support STARTTLS")
+		}
+
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+
+	if t.auth != nil {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return errors.New("mailer SMTP server doesn't support AUTH")
+		}
+
+		if err := c.Auth(t.auth); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Mail(sender); err != nil {
+		return err
+	}
+
+	for _, recipient := range recipients {
+		if err := c.Rcpt(recipient); err != nil {
+			return err
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Write(raw); err != nil {
+		return err
+	}
+
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return c.Quit()
+}
+
+// NewSendmailMailTransport initializes SendmailMailTransport instance with MAILER_SENDMAIL_PATH.
+func NewSendmailMailTransport(config *Config) *SendmailMailTransport {
+	return &SendmailMailTransport{path: config.MailerSendmailPath}
+}
+
+// Send delivers the email by piping it into `sendmail -i -f <sender> -- <recipients...>`.
+func (t *SendmailMailTransport) Send(mail Mail, email *email.Email) error {
+	sender, recipients, err := mailEnvelope(email)
+	if err != nil {
+		return err
+	}
+
+	raw, err := email.Bytes()
+	if err != nil {
+		return err
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(t.path, append([]string{"-i", "-f", sender, "--"}, recipients...)...)
+	cmd.Stdin = bytes.NewReader(raw)
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return nil
+}
+
+// NewFileMailTransport initializes FileMailTransport instance with MAILER_FILE_PATH.
+func NewFileMailTransport(config *Config) *FileMailTransport {
+	return &FileMailTransport{path: config.MailerFilePath}
+}
+
+// Path returns the folder that the `.eml` files are written into.
+func (t *FileMailTransport) Path() string {
+	return t.path
+}
+
+// Send writes the email into `<MAILER_FILE_PATH>/<timestamp>_<template>.eml`.
+func (t *FileMailTransport) Send(mail Mail, email *email.Email) error {
+	raw, err := email.Bytes()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(t.path, 0777); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%d_%s.eml", time.Now().UnixNano(), strings.ReplaceAll(mail.Template, "/", "_"))
+	return ioutil.WriteFile(filepath.Join(t.path, name), raw, 0644)
+}
+
+// NewMemoryMailTransport initializes MemoryMailTransport instance.
+func NewMemoryMailTransport() *MemoryMailTransport {
+	return &MemoryMailTransport{mu: &sync.Mutex{}}
+}
+
+// Deliveries returns the mails that are sent via the transport.
+func (t *MemoryMailTransport) Deliveries() []Mail {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.deliveries
+}
+
+// Emails returns the composed emails that are sent via the transport, i.e. with the interceptors' changes.
+func (t *MemoryMailTransport) Emails() []*email.Email {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.emails
+}
+
+// Send keeps the mail and its composed email in memory.
+func (t *MemoryMailTransport) Send(mail Mail, email *email.Email) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.deliveries = append(t.deliveries, mail)
+	t.emails = append(t.emails, email)
+	return nil
+}
+
+func (a *smtpLoginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS && !isLocalhost(server.Name) {
+		return "", nil, errors.New("unencrypted connection")
+	}
+
+	return "LOGIN", nil, nil
+}
+
+func (a *smtpLoginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+
+	switch strings.ToLower(strings.TrimSpace(string(fromServer))) {
+	case "username:":
+		return []byte(a.username), nil
+	case "password:":
+		return []byte(a.password), nil
+	}
+
+	return nil, fmt.Errorf("unexpected server challenge: %s", fromServer)
+}
+
+func isLocalhost(name string) bool {
+	return name == "localhost" || name == "127.0.0.1" || name == "::1"
+}
+
+func newMailTransport(config *Config) (MailTransport, error) {
+	if config.AppyEnv == "test" {
+		return NewMemoryMailTransport(), nil
+	}
+
+	switch config.MailerTransport {
+	case "smtp":
+		return NewSMTPMailTransport(config)
+	case "sendmail":
+		return NewSendmailMailTransport(config), nil
+	case "file":
+		return NewFileMailTransport(config), nil
+	case "memory":
+		return NewMemoryMailTransport(), nil
+	}
+
+	return nil, fmt.Errorf("mailer transport '%s' is not supported", config.MailerTransport)
+}
+
+// mailEnvelope returns the envelope sender which is the email's sender or from address, and the envelope recipients
+// which are the email's to/cc/bcc addresses.
+func mailEnvelope(email *email.Email) (string, []string, error) {
+	from := email.Sender
+	if from == "" {
+		from = email.From
+	}
+
+	sender, err := netmail.ParseAddress(from)
+	if err != nil {
+		return "", nil, err
+	}
+
+	recipients := []string{}
+	for _, list := range [][]string{email.To, email.Cc, email.Bcc} {
+		for _, recipient := range list {
+			addr, err := netmail.ParseAddress(recipient)
+			if err != nil {
+				return "", nil, err
+			}
+
+			recipients = append(recipients, addr.Address)
+		}
+	}
+
+	if len(recipients) < 1 {
+		return "", nil, errors.New("mail must have at least 1 recipient")
+	}
+
+	return sender.Address, recipients, nil
+}
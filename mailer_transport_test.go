@@ -0,0 +1,303 @@
+package appy
+
+import (
+	"bufio"
+	"encoding/base64"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jordan-wright/email"
+)
+
+type MailTransportSuite struct {
+	TestSuite
+	config *Config
+	email  *email.Email
+	mail   Mail
+}
+
+func (s *MailTransportSuite) SetupTest() {
+	s.config = &Config{
+		AppyEnv:               "development",
+		MailerTransport:       "smtp",
+		MailerSMTPDialTimeout: time.Second,
+		MailerSMTPTLSMode:     "none",
+		MailerSMTPAuth:        "none",
+		MailerSendmailPath:    "/usr/sbin/sendmail",
+	}
+
+	s.mail = Mail{
+		From:     "Support <support@appist.io>",
+		To:       []string{"jane@appist.io"},
+		Cc:       []string{"John <john@appist.io>"},
+		Bcc:      []string{"mary@appist.io"},
+		Subject:  "Welcome",
+		Template: "mailers/user/welcome",
+	}
+
+	s.email = &email.Email{
+		From:    s.mail.From,
+		To:      s.mail.To,
+		Cc:      s.mail.Cc,
+		Bcc:     s.mail.Bcc,
+		Subject: s.mail.Subject,
+		Text:    []byte("Hello"),
+	}
+}
+
+func (s *MailTransportSuite) TestNewMailTransport() {
+	tt := map[string]interface{}{
+		"smtp":     &SMTPMailTransport{},
+		"sendmail": &SendmailMailTransport{},
+		"file":     &FileMailTransport{},
+		"memory":   &MemoryMailTransport{},
+	}
+
+	for name, expected := range tt {
+		s.config.MailerTransport = name
+		transport, err := newMailTransport(s.config)
+		s.Nil(err)
+		s.IsType(expected, transport)
+	}
+
+	s.config.MailerTransport = "pigeon"
+	transport, err := newMailTransport(s.config)
+	s.EqualError(err, "mailer transport 'pigeon' is not supported")
+	s.Nil(transport)
+
+	s.config.AppyEnv = "test"
+	transport, err = newMailTransport(s.config)
+	s.Nil(err)
+	s.IsType(&MemoryMailTransport{}, transport)
+
+	s.config.AppyEnv = "development"
+	s.config.MailerTransport = "smtp"
+	s.config.MailerSMTPTLSMode = "ssl"
+	_, err = newMailTransport(s.config)
+	s.EqualError(err, "mailer SMTP TLS mode 'ssl' is not supported")
+
+	s.config.MailerSMTPTLSMode = "starttls"
+	s.config.MailerSMTPAuth = "ntlm"
+	_, err = newMailTransport(s.config)
+	s.EqualError(err, "mailer SMTP auth 'ntlm' is not supported")
+}
+
+func (s *MailTransportSuite) TestSMTPMailTransport() {
+	for _, auth := range []string{"none", "plain", "login", "cram-md5"} {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		s.Nil(err)
+
+		received := make(chan []string, 1)
+		go fakeSMTPServer(ln, received)
+
+		s.config.MailerSMTPAddr = ln.Addr().String()
+		s.config.MailerSMTPAuth = auth
+		s.config.MailerSMTPPlainAuthUsername = "user"
+		s.config.MailerSMTPPlainAuthPassword = "secret"
+		transport, err := NewSMTPMailTransport(s.config)
+		s.Nil(err)
+		s.Nil(transport.Send(s.mail, s.email))
+
+		cmds := <-received
+		s.Contains(cmds, "MAIL FROM:<support@appist.io>")
+		s.Contains(cmds, "RCPT TO:<jane@appist.io>")
+		s.Contains(cmds, "RCPT TO:<john@appist.io>")
+		s.Contains(cmds, "RCPT TO:<mary@appist.io>")
+
+		if auth != "none" {
+			s.Contains(strings.Join(cmds, "\n"), "AUTH "+strings.ToUpper(auth))
+		}
+
+		ln.Close()
+	}
+}
+
+func (s *MailTransportSuite) TestSMTPMailTransportWithoutSTARTTLSSupport() {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	s.Nil(err)
+	defer ln.Close()
+
+	go fakeSMTPServer(ln, make(chan []string, 1))
+
+	s.config.MailerSMTPAddr = ln.Addr().String()
+	s.config.MailerSMTPTLSMode = "starttls"
+	transport, err := NewSMTPMailTransport(s.config)
+	s.Nil(err)
+	s.EqualError(transport.Send(s.mail, s.email), "mailer SMTP server doesn't support STARTTLS")
+}
+
+func (s *MailTransportSuite) TestSMTPMailTransportWithoutAUTHSupport() {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	s.Nil(err)
+	defer ln.Close()
+
+	received := make(chan []string, 1)
+	go fakeSMTPServer(ln, received, "8BITMIME")
+
+	s.config.MailerSMTPAddr = ln.Addr().String()
+	s.config.MailerSMTPAuth = "plain"
+	s.config.MailerSMTPPlainAuthUsername = "user"
+	s.config.MailerSMTPPlainAuthPassword = "secret"
+	transport, err := NewSMTPMailTransport(s.config)
+	s.Nil(err)
+	s.EqualError(transport.Send(s.mail, s.email), "mailer SMTP server doesn't support AUTH")
+	s.NotContains(<-received, "MAIL FROM:<support@appist.io>")
+}
+
+func (s *MailTransportSuite) TestSMTPMailTransportWithoutCredentials() {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	s.Nil(err)
+	defer ln.Close()
+
+	received := make(chan []string, 1)
+	go fakeSMTPServer(ln, received, "PIPELINING")
+
+	s.config.MailerSMTPAddr = ln.Addr().String()
+	s.config.MailerSMTPAuth = "plain"
+	s.config.MailerSMTPPlainAuthUsername = ""
+	s.config.MailerSMTPPlainAuthPassword = ""
+	transport, err := NewSMTPMailTransport(s.config)
+	s.Nil(err)
+	s.Nil(transport.Send(s.mail, s.email))
+
+	cmds := <-received
+	s.Contains(cmds, "MAIL FROM:<support@appist.io>")
+	s.NotContains(strings.Join(cmds, "\n"), "AUTH ")
+}
+
+func (s *MailTransportSuite) TestSendmailMailTransport() {
+	dir, err := ioutil.TempDir("", "sendmail")
+	s.Nil(err)
+	defer os.RemoveAll(dir)
+
+	script := filepath.Join(dir, "sendmail")
+	s.Nil(ioutil.WriteFile(script, []byte("#!/bin/sh\necho \"$@\" > "+dir+"/args\ncat > "+dir+"/stdin\n"), 0755))
+
+	s.config.MailerSendmailPath = script
+	transport := NewSendmailMailTransport(s.config)
+	s.Nil(transport.Send(s.mail, s.email))
+
+	args, err := ioutil.ReadFile(filepath.Join(dir, "args"))
+	s.Nil(err)
+	s.Equal("-i -f support@appist.io -- jane@appist.io john@appist.io mary@appist.io\n", string(args))
+
+	stdin, err := ioutil.ReadFile(filepath.Join(dir, "stdin"))
+	s.Nil(err)
+	s.Contains(string(stdin), "Subject: Welcome")
+
+	s.config.MailerSendmailPath = filepath.Join(dir, "missing")
+	transport = NewSendmailMailTransport(s.config)
+	s.NotNil(transport.Send(s.mail, s.email))
+}
+
+func (s *MailTransportSuite) TestFileMailTransport() {
+	dir, err := ioutil.TempDir("", "mails")
+	s.Nil(err)
+	defer os.RemoveAll(dir)
+
+	s.config.MailerFilePath = filepath.Join(dir, "tmp")
+	transport := NewFileMailTransport(s.config)
+	s.Equal(s.config.MailerFilePath, transport.Path())
+	s.Nil(transport.Send(s.mail, s.email))
+
+	files, err := ioutil.ReadDir(s.config.MailerFilePath)
+	s.Nil(err)
+	s.Equal(1, len(files))
+	s.True(strings.HasSuffix(files[0].Name(), "_mailers_user_welcome.eml"))
+
+	content, err := ioutil.ReadFile(filepath.Join(s.config.MailerFilePath, files[0].Name()))
+	s.Nil(err)
+	s.Contains(string(content), "Subject: Welcome")
+}
+
+func (s *MailTransportSuite) TestMemoryMailTransport() {
+	transport := NewMemoryMailTransport()
+	s.Equal(0, len(transport.Deliveries()))
+	s.Nil(transport.Send(s.mail, s.email))
+	s.Equal([]Mail{s.mail}, transport.Deliveries())
+	s.Equal([]*email.Email{s.email}, transport.Emails())
+}
+
+func TestMailTransportSuite(t *testing.T) {
+	RunTestSuite(t, new(MailTransportSuite))
+}
+
+// fakeSMTPServer accepts a single connection and records the commands that it receives. It advertises the AUTH
+// extension unless the extensions are specified.
+func fakeSMTPServer(ln net.Listener, received chan<- []string, extensions ...string) {
+	if len(extensions) < 1 {
+		extensions = []string{"AUTH PLAIN LOGIN CRAM-MD5"}
+	}
+
+	conn, err := ln.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	cmds := []string{}
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+	reply := func(line string) {
+		w.WriteString(line + "\r\n")
+		w.Flush()
+	}
+
+	reply("220 localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			break
+		}
+
+		line = strings.TrimRight(line, "\r\n")
+		cmds = append(cmds, line)
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+
+		switch verb {
+		case "EHLO":
+			reply("250-localhost")
+			for i, extension := range extensions {
+				if i == len(extensions)-1 {
+					reply("250 " + extension)
+				} else {
+					reply("250-" + extension)
+				}
+			}
+		case "AUTH":
+			switch strings.ToUpper(strings.Fields(line)[1]) {
+			case "LOGIN":
+				reply("334 " + base64.StdEncoding.EncodeToString([]byte("Username:")))
+				r.ReadString('\n')
+				reply("334 " + base64.StdEncoding.EncodeToString([]byte("Password:")))
+				r.ReadString('\n')
+			case "CRAM-MD5":
+				reply("334 " + base64.StdEncoding.EncodeToString([]byte("<1@localhost>")))
+				r.ReadString('\n')
+			}
+			reply("235 Authentication successful")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			for {
+				data, err := r.ReadString('\n')
+				if err != nil || data == ".\r\n" {
+					break
+				}
+			}
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+			received <- cmds
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+
+	received <- cmds
+}
@@ -36,7 +36,7 @@ func (s *AttachMailerSuite) SetupTest() {
 	s.config = NewConfig(s.asset, s.logger, s.support)
 	s.i18n = NewI18n(s.asset, s.config, s.logger)
 	s.server = NewServer(s.asset, s.config, s.logger, s.support)
-	s.mailer = NewMailer(s.asset, s.config, s.i18n, s.logger, s.server, nil)
+	s.mailer = NewMailer(s.asset, s.config, s.i18n, s.logger, s.server, nil, nil)
 }
 
 func (s *AttachMailerSuite) TearDownTest() {
@@ -0,0 +1,13 @@
+package appy
+
+var (
+	workerCtxKey = ContextKey("worker")
+)
+
+// AttachWorker attaches the worker to the request context.
+func AttachWorker(worker *Worker) HandlerFunc {
+	return func(c *Context) {
+		c.Set(workerCtxKey.String(), worker)
+		c.Next()
+	}
+}
@@ -0,0 +1,61 @@
+package appy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+type AttachWorkerSuite struct {
+	TestSuite
+	asset   *Asset
+	config  *Config
+	logger  *Logger
+	support Supporter
+	worker  *Worker
+}
+
+func (s *AttachWorkerSuite) SetupTest() {
+	os.Setenv("APPY_ENV", "test")
+	os.Setenv("APPY_MASTER_KEY", "481e5d98a31585148b8b1dfb6a3c0465")
+	os.Setenv("HTTP_CSRF_SECRET", "481e5d98a31585148b8b1dfb6a3c0465")
+	os.Setenv("HTTP_SESSION_SECRETS", "481e5d98a31585148b8b1dfb6a3c0465")
+
+	s.support = &Support{}
+	s.logger, _, _ = NewFakeLogger()
+	s.asset = NewAsset(http.Dir("testdata/app"), map[string]string{
+		"docker": "testdata/app/.docker",
+		"config": "testdata/app/configs",
+		"locale": "testdata/app/pkg/locales",
+		"view":   "testdata/app/pkg/views",
+		"web":    "testdata/app/web",
+	}, "")
+	s.config = NewConfig(s.asset, s.logger, s.support)
+	s.worker = NewWorker(s.config, s.logger)
+}
+
+func (s *AttachWorkerSuite) TearDownTest() {
+	os.Unsetenv("APPY_ENV")
+	os.Unsetenv("APPY_MASTER_KEY")
+	os.Unsetenv("HTTP_CSRF_SECRET")
+	os.Unsetenv("HTTP_SESSION_SECRETS")
+}
+
+func (s *AttachWorkerSuite) TestExistence() {
+	c, _ := NewTestContext(httptest.NewRecorder())
+	AttachWorker(s.worker)(c)
+	s.NotNil(c.Get(workerCtxKey.String()))
+
+	job, err := c.EnqueueJob("user.welcome", H{"id": 1})
+	s.Nil(err)
+
+	pending, err := s.worker.Pending(DefaultJobQueue)
+	s.Nil(err)
+	s.Equal(1, len(pending))
+	s.Equal(job.ID, pending[0].ID)
+}
+
+func TestAttachWorkerSuite(t *testing.T) {
+	RunTestSuite(t, new(AttachWorkerSuite))
+}
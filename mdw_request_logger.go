@@ -1,17 +1,31 @@
 package appy
 
 import (
+	"context"
+	"fmt"
 	"net/http"
 	"strings"
 	"time"
 )
 
 // RequestLogger is a middleware that logs the start and end of each request, along with some useful data about what
-// was requested, what the response status was, and how long it took to return.
+// was requested, what the response status was, how long it took to return and how many queries it ran with the
+// request's context. Note that the queries only count if they run with the request's context, e.g.
+// `db.ModelContext(c, &users)` or `db.ExecContext(c.Request.Context(), query)`, but not `db.Model(&users)`.
+//
+// In the debug build, the identical query shapes that run at least DB_N_PLUS_ONE_THRESHOLD times in a request are
+// logged at warn level as the likely N+1 queries.
 func RequestLogger(config *Config, logger *Logger) HandlerFunc {
 	return func(c *Context) {
 		requestID, _ := c.Get(requestIDCtxKey.String())
 		start := time.Now()
+
+		stats := newDBQueryStats(fmt.Sprint(requestID))
+		c.Set(dbQueryStatsCtxKey.String(), stats)
+		if c.Request != nil {
+			c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), dbQueryStatsCtxKey, stats))
+		}
+
 		c.Next()
 
 		r := c.Request
@@ -20,8 +34,17 @@ func RequestLogger(config *Config, logger *Logger) HandlerFunc {
 			scheme = "https"
 		}
 
-		logger.Infof("[HTTP] %s %s '%s://%s%s %s' from %s - %d %dB in %s", requestID, r.Method, scheme, r.Host, filterParams(r, config),
-			r.Proto, r.RemoteAddr, c.Writer.Status(), c.Writer.Size(), time.Since(start))
+		if IsDebugBuild() {
+			shapes, counts := stats.repeatedShapes(config.DBNPlusOneThreshold)
+			for i, shape := range shapes {
+				logger.Warnf("[SQL] %s likely N+1 queries, the same query ran %d times: %s", requestID, counts[i], shape)
+			}
+		}
+
+		queryCount, queryDuration := stats.totals()
+		logger.Infof("[HTTP] %s %s '%s://%s%s %s' from %s - %d %dB in %s (DB: %d queries in %s)", requestID, r.Method, scheme,
+			r.Host, filterParams(r, config), r.Proto, r.RemoteAddr, c.Writer.Status(), c.Writer.Size(), time.Since(start),
+			queryCount, queryDuration)
 	}
 }
 
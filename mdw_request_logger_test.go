@@ -8,6 +8,9 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"testing"
+	"time"
+
+	"github.com/go-pg/pg/v9"
 )
 
 type RequestLoggerSuite struct {
@@ -61,6 +64,71 @@ func (s *RequestLoggerSuite) TestRequestLogger() {
 	RequestLogger(config, s.logger)(c)
 	s.writer.Flush()
 	s.Contains(s.buffer.String(), "[HTTP] 1234 GET 'https://localhost HTTP/2.0' from 127.0.0.1 - 200")
+	s.Contains(s.buffer.String(), "(DB: 0 queries in 0s)")
+}
+
+func (s *RequestLoggerSuite) TestRequestLoggerWithDBQueries() {
+	config := &Config{DBNPlusOneThreshold: 3}
+	db := NewDB(&DBConfig{
+		Options: pg.Options{Addr: "0.0.0.0:15432", User: "postgres", Password: "whatever", Database: "postgres"},
+	}, nil, s.logger, &Support{})
+	s.Nil(db.Connect())
+	defer db.Close()
+
+	// The N+1 and slow queries are logged even if the DB logging is disabled.
+	s.logger.SetDBLogging(false)
+	defer s.logger.SetDBLogging(true)
+
+	server := NewServer(NewAsset(nil, nil, ""), config, s.logger, &Support{})
+	server.Use(RequestID())
+	server.Use(RequestLogger(config, s.logger))
+	server.GET("/databases", func(c *Context) {
+		for i := 1; i <= 3; i++ {
+			_, err := db.ExecContext(c, "SELECT * FROM pg_database WHERE oid = ? AND datname = ?", i, "appy")
+			s.Nil(err)
+		}
+
+		_, err := db.ExecContext(c.Request.Context(), "SELECT * FROM pg_database WHERE oid IN (?)", pg.In([]int{1, 2}))
+		s.Nil(err)
+		_, err = db.ExecContext(c.Request.Context(), "SELECT * FROM pg_database WHERE oid IN (?)", pg.In([]int{3}))
+		s.Nil(err)
+
+		// The query which doesn't run with the request's context isn't counted.
+		_, err = db.Exec("SELECT * FROM pg_tablespace")
+		s.Nil(err)
+	})
+
+	server.TestHTTPRequest("GET", "/databases", H{"X-Request-Id": "1234"}, nil)
+	s.writer.Flush()
+	s.Contains(s.buffer.String(), "[HTTP] 1234 GET ")
+	s.Contains(s.buffer.String(), "(DB: 5 queries in ")
+	s.Contains(s.buffer.String(), "[SQL] 1234 likely N+1 queries, the same query ran 3 times: SELECT * FROM pg_database WHERE oid = ? AND datname = ?")
+	s.NotContains(s.buffer.String(), "likely N+1 queries, the same query ran 2 times")
+	s.NotContains(s.buffer.String(), "slow query")
+
+	s.buffer.Reset()
+	s.logger.SetDBSlowQueryThreshold(time.Nanosecond)
+	defer s.logger.SetDBSlowQueryThreshold(0)
+
+	Build = ReleaseBuild
+	defer func() {
+		Build = DebugBuild
+	}()
+
+	server.TestHTTPRequest("GET", "/databases", H{"X-Request-Id": "5678"}, nil)
+	s.writer.Flush()
+	s.Contains(s.buffer.String(), "[SQL] 5678 slow query in ")
+	s.Contains(s.buffer.String(), ": SELECT * FROM pg_database WHERE oid = ? AND datname = ?")
+	s.NotContains(s.buffer.String(), "'appy'")
+	s.Contains(s.buffer.String(), "[SQL] slow query in ")
+	s.Contains(s.buffer.String(), ": SELECT * FROM pg_tablespace")
+	s.NotContains(s.buffer.String(), "likely N+1 queries")
+}
+
+func (s *RequestLoggerSuite) TestDBQueryShape() {
+	s.Equal("SELECT * FROM users WHERE id = ? AND email = ?", dbQueryShape("SELECT * FROM users WHERE id = 1 AND email = 'john''s@appist.io'"))
+	s.Equal("SELECT * FROM users WHERE id IN (?)", dbQueryShape("SELECT * FROM users WHERE id IN (1, 2, 3)"))
+	s.Equal("SELECT * FROM users2 WHERE score > ?", dbQueryShape("SELECT * FROM users2 WHERE score > 1.5"))
 }
 
 func TestRequestLoggerSuite(t *testing.T) {
@@ -7,6 +7,7 @@ import (
 
 	"github.com/appist/appy/internal/sessionstore"
 	ginsessions "github.com/gin-contrib/sessions"
+	"github.com/go-pg/pg/v9"
 	"github.com/gomodule/redigo/redis"
 	"github.com/gorilla/context"
 	gorsessions "github.com/gorilla/sessions"
@@ -16,6 +17,19 @@ var (
 	sessionManagerCtxKey = ContextKey("sessionManager")
 )
 
+// SessionSerializer is an interface for custom session serializers which encode the session values for the session
+// stores that keep them on the server side, e.g.
+//
+//	sessionStore.SetSerializer(&MySerializer{})
+//
+// The custom serializer can implement `Name() string` to prefix its payload with the `appy:<version>:<name>:` header
+// so that its sessions are still readable after switching to another serializer.
+type SessionSerializer = sessionstore.SessionSerializer
+
+// SessionStoreStats contains the session store's connection pool statistics which is returned by the session stores
+// that implement `Stats() SessionStoreStats`, e.g. the built-in redis and postgres stores.
+type SessionStoreStats = sessionstore.Stats
+
 // SessionStore is an interface for custom session stores.
 type SessionStore interface {
 	gorsessions.Store
@@ -28,6 +42,12 @@ type SessionStore interface {
 
 	// SetKeyPrefix sets the prefix for the store key, not available for CookieStore.
 	SetKeyPrefix(p string)
+
+	// Serializer returns the serializer for the session values, not available for CookieStore.
+	Serializer() SessionSerializer
+
+	// SetSerializer sets the serializer for the session values, not available for CookieStore.
+	SetSerializer(serializer SessionSerializer)
 }
 
 // Sessioner stores the values and optional configuration for a session.
@@ -75,12 +95,13 @@ type Sessioner interface {
 
 // Session provides the session functionality for a single HTTP request.
 type Session struct {
-	name    string
-	request *http.Request
-	store   SessionStore
-	session *gorsessions.Session
-	written bool
-	writer  http.ResponseWriter
+	keyPrefix string
+	name      string
+	request   *http.Request
+	store     SessionStore
+	session   *gorsessions.Session
+	written   bool
+	writer    http.ResponseWriter
 }
 
 // RedisPoolConfig keeps the redis connection pool config.
@@ -91,28 +112,58 @@ type RedisPoolConfig struct {
 	IdleTimeout, MaxConnLifetime time.Duration
 }
 
-// SessionManager is a middleware that provides the session management functionality.
+// SessionManager is a middleware that provides the session management functionality. The session store is created
+// with HTTP_SESSION_PROVIDER once when the middleware is created and shared across the requests, and it panics if the
+// session store fails to be created. Note that the postgres provider is only available to the app's session store, i.e.
+// Server.SessionStore, as it requires the DB manager.
 func SessionManager(config *Config) HandlerFunc {
-	return func(c *Context) {
-		sessionStore, err := newSessionStore(config)
-		if err != nil {
-			panic(err)
-		}
+	sessionStore, err := newSessionStore(config, nil)
+	if err != nil {
+		panic(err)
+	}
+
+	return SessionManagerWithStore(config, sessionStore)
+}
 
-		s := &Session{config.HTTPSessionName, c.Request, sessionStore, nil, false, c.Writer}
+// SessionManagerWithStore is a middleware that provides the session management functionality with the session store
+// which is shared across the requests.
+func SessionManagerWithStore(config *Config, sessionStore SessionStore) HandlerFunc {
+	return func(c *Context) {
+		s := &Session{name: config.HTTPSessionName, request: c.Request, store: sessionStore, writer: c.Writer}
 		c.Set(sessionManagerCtxKey.String(), s)
 		defer context.Clear(c.Request)
 		c.Next()
 	}
 }
 
-func newSessionStore(config *Config) (SessionStore, error) {
+// RegisterSessionSerializer registers the custom session serializer with the name so that it can be picked by
+// HTTP_SESSION_SERIALIZER and the sessions written by it can be read after switching to another serializer.
+func RegisterSessionSerializer(name string, serializer SessionSerializer) {
+	sessionstore.RegisterSerializer(name, serializer)
+}
+
+func newSessionStore(config *Config, dbManager *DBManager) (SessionStore, error) {
 	var (
 		sessionStore SessionStore
 		err          error
 	)
 
-	switch provider := config.HTTPSessionProvider; provider {
+	provider := config.HTTPSessionProvider
+	if provider == "cookie" && config.HTTPSessionSerializer != "" {
+		return nil, fmt.Errorf("session serializer '%s' is not supported by the cookie session provider", config.HTTPSessionSerializer)
+	}
+
+	serializerName := config.HTTPSessionSerializer
+	if serializerName == "" {
+		serializerName = "gob"
+	}
+
+	serializer, err := sessionstore.NewSerializer(serializerName)
+	if err != nil {
+		return nil, err
+	}
+
+	switch provider {
 	case "cookie":
 		sessionStore = sessionstore.NewCookieStore(config.HTTPSessionSecrets...)
 	case "redis":
@@ -131,11 +182,31 @@ func newSessionStore(config *Config) (SessionStore, error) {
 			NewRedisPool(redisPoolConfig),
 			config.HTTPSessionSecrets...,
 		)
+	case "memory":
+		sessionStore = sessionstore.NewMemoryStore(config.HTTPSessionMemoryMaxEntries, config.HTTPSessionSecrets...)
+	case "postgres":
+		var db *DB
+		if dbManager != nil {
+			db = dbManager.DB(config.HTTPSessionPostgresDB)
+		}
+
+		if db == nil {
+			err = fmt.Errorf("database '%s' for the postgres session store is not defined", config.HTTPSessionPostgresDB)
+			break
+		}
+
+		sessionStore = sessionstore.NewPostgresStore(
+			func() *pg.DB { return db.DB },
+			config.HTTPSessionPostgresTable,
+			config.HTTPSessionPostgresCleanupInterval,
+			config.HTTPSessionSecrets...,
+		)
 	default:
 		err = fmt.Errorf("session provider '%s' is not supported", provider)
 	}
 
 	if sessionStore != nil {
+		sessionStore.SetSerializer(serializer)
 		sessionStore.Options(ginsessions.Options{
 			Domain:   config.HTTPSessionDomain,
 			HttpOnly: config.HTTPSessionHTTPOnly,
@@ -260,12 +331,18 @@ func (s *Session) Key() string {
 
 // KeyPrefix returns the key prefix for the session, not available for CookieStore.
 func (s *Session) KeyPrefix() string {
+	if s.keyPrefix != "" {
+		return s.keyPrefix
+	}
+
 	return s.store.KeyPrefix()
 }
 
-// SetKeyPrefix sets the key prefix for the session, not available for CookieStore.
+// SetKeyPrefix sets the key prefix for the session which only applies to the current request as the session store is
+// shared across the requests, not available for CookieStore.
 func (s *Session) SetKeyPrefix(p string) {
-	s.store.SetKeyPrefix(p)
+	s.keyPrefix = p
+	sessionstore.SetRequestKeyPrefix(s.request, p)
 }
 
 // Set sets the session value associated to the given key.
@@ -1,12 +1,16 @@
 package appy
 
 import (
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
+	"github.com/appist/appy/internal/sessionstore"
 	ginsessions "github.com/gin-contrib/sessions"
+	gorsessions "github.com/gorilla/sessions"
 )
 
 type SessionManagerSuite struct {
@@ -15,6 +19,7 @@ type SessionManagerSuite struct {
 	config   *Config
 	logger   *Logger
 	recorder *httptest.ResponseRecorder
+	server   *Server
 }
 
 func testSessionOps(s *SessionManagerSuite, session Sessioner) {
@@ -55,6 +60,7 @@ func (s *SessionManagerSuite) SetupTest() {
 	s.asset = NewAsset(http.Dir("testdata"), nil, "")
 	s.config = NewConfig(s.asset, s.logger, &Support{})
 	s.recorder = httptest.NewRecorder()
+	s.server = NewServer(s.asset, s.config, s.logger, &Support{})
 }
 
 func (s *SessionManagerSuite) TearDownTest() {
@@ -68,7 +74,188 @@ func (s *SessionManagerSuite) TestSessionUnknownStore() {
 	c.Request = &http.Request{}
 
 	s.config.HTTPSessionProvider = "unknown"
-	s.Panics(func() { SessionManager(s.config)(c) })
+	s.PanicsWithError("session provider 'unknown' is not supported", func() { SessionManager(s.config) })
+}
+
+func (s *SessionManagerSuite) TestSessionUnknownSerializer() {
+	s.config.HTTPSessionProvider = "memory"
+	s.config.HTTPSessionSerializer = "xml"
+	s.PanicsWithError("session serializer 'xml' is not supported", func() { SessionManager(s.config) })
+
+	_, err := newSessionStore(s.config, nil)
+	s.EqualError(err, "session serializer 'xml' is not supported")
+}
+
+func (s *SessionManagerSuite) TestSessionSerializers() {
+	for _, name := range []string{"gob", "json", "msgpack"} {
+		serializer, err := sessionstore.NewSerializer(name)
+		s.Nil(err)
+
+		session := gorsessions.NewSession(nil, "_session")
+		session.Values["user"] = "john"
+		session.Values["admin"] = true
+
+		data, err := sessionstore.Encode(serializer, session)
+		s.Nil(err)
+
+		decoded := gorsessions.NewSession(nil, "_session")
+		migrate, err := sessionstore.Decode(data, decoded, serializer)
+		s.Nil(err)
+		s.Equal(false, migrate)
+		s.Equal(session.Values, decoded.Values)
+	}
+
+	session := gorsessions.NewSession(nil, "_session")
+	session.Values["user"] = "john"
+
+	// The json payload is kept as is so that it can be read by the other services.
+	data, err := sessionstore.Encode(sessionstore.JSONSerializer{}, session)
+	s.Nil(err)
+	s.Equal(`{"user":"john"}`, string(data))
+
+	msgpackData, err := sessionstore.Encode(sessionstore.MsgpackSerializer{}, session)
+	s.Nil(err)
+	s.Contains(string(msgpackData), "appy:1:msgpack:")
+
+	// The payload which is written by another serializer is readable and should be migrated.
+	decoded := gorsessions.NewSession(nil, "_session")
+	migrate, err := sessionstore.Decode(data, decoded, sessionstore.MsgpackSerializer{})
+	s.Nil(err)
+	s.Equal(true, migrate)
+	s.Equal("john", decoded.Values["user"])
+
+	decoded = gorsessions.NewSession(nil, "_session")
+	migrate, err = sessionstore.Decode(msgpackData, decoded, sessionstore.JSONSerializer{})
+	s.Nil(err)
+	s.Equal(true, migrate)
+	s.Equal("john", decoded.Values["user"])
+
+	gobData, err := sessionstore.Encode(sessionstore.GobSerializer{}, session)
+	s.Nil(err)
+
+	decoded = gorsessions.NewSession(nil, "_session")
+	migrate, err = sessionstore.Decode(gobData, decoded, sessionstore.JSONSerializer{})
+	s.Nil(err)
+	s.Equal(true, migrate)
+	s.Equal("john", decoded.Values["user"])
+
+	decoded = gorsessions.NewSession(nil, "_session")
+	migrate, err = sessionstore.Decode(gobData, decoded, sessionstore.GobSerializer{})
+	s.Nil(err)
+	s.Equal(false, migrate)
+	s.Equal("john", decoded.Values["user"])
+
+	_, err = sessionstore.Decode([]byte("appy:2:json:{}"), decoded, sessionstore.JSONSerializer{})
+	s.EqualError(err, "session payload version '2' is not supported")
+
+	_, err = sessionstore.Decode([]byte("appy:1:xml:<user/>"), decoded, sessionstore.JSONSerializer{})
+	s.EqualError(err, "session serializer 'xml' is not supported")
+
+	_, err = sessionstore.Decode([]byte("appy:1"), decoded, sessionstore.JSONSerializer{})
+	s.EqualError(err, "session payload header is invalid")
+
+	session.Values[1] = "one"
+	_, err = sessionstore.Encode(sessionstore.JSONSerializer{}, session)
+	s.EqualError(err, "session key '1' must be a string to be serialized with json")
+}
+
+type testSessionSerializer struct {
+	sessionstore.JSONSerializer
+}
+
+func (s testSessionSerializer) Name() string {
+	return "test"
+}
+
+type testUnnamedSessionSerializer struct {
+	sessionstore.JSONSerializer
+}
+
+func (s *SessionManagerSuite) TestSessionCustomSerializers() {
+	session := gorsessions.NewSession(nil, "_session")
+	session.Values["user"] = "john"
+
+	// The custom serializer without name is used as is.
+	data, err := sessionstore.Encode(testUnnamedSessionSerializer{}, session)
+	s.Nil(err)
+	s.Equal(`{"user":"john"}`, string(data))
+
+	decoded := gorsessions.NewSession(nil, "_session")
+	migrate, err := sessionstore.Decode(data, decoded, testUnnamedSessionSerializer{})
+	s.Nil(err)
+	s.Equal(false, migrate)
+	s.Equal("john", decoded.Values["user"])
+
+	// The named custom serializer opts in the payload header which is readable once it is registered.
+	data, err = sessionstore.Encode(testSessionSerializer{}, session)
+	s.Nil(err)
+	s.Equal(`appy:1:test:{"user":"john"}`, string(data))
+
+	decoded = gorsessions.NewSession(nil, "_session")
+	_, err = sessionstore.Decode(data, decoded, sessionstore.GobSerializer{})
+	s.EqualError(err, "session serializer 'test' is not supported")
+
+	RegisterSessionSerializer("test", testSessionSerializer{})
+	migrate, err = sessionstore.Decode(data, decoded, sessionstore.GobSerializer{})
+	s.Nil(err)
+	s.Equal(true, migrate)
+	s.Equal("john", decoded.Values["user"])
+
+	s.config.HTTPSessionProvider = "memory"
+	s.config.HTTPSessionSerializer = "test"
+	sessionStore, err := newSessionStore(s.config, nil)
+	s.Nil(err)
+	s.Equal(testSessionSerializer{}, sessionStore.Serializer())
+}
+
+func (s *SessionManagerSuite) TestSessionCookieStoreSerializer() {
+	sessionStore, err := newSessionStore(s.config, nil)
+	s.Nil(err)
+
+	sessionStore.SetSerializer(sessionstore.JSONSerializer{})
+	s.Nil(sessionStore.Serializer())
+
+	s.config.HTTPSessionSerializer = "json"
+	_, err = newSessionStore(s.config, nil)
+	s.EqualError(err, "session serializer 'json' is not supported by the cookie session provider")
+}
+
+func (s *SessionManagerSuite) TestSessionStoreSharedAcrossRequests() {
+	s.config.HTTPSessionProvider = "unknown"
+	s.server.setupSessionStore(nil)
+	s.Nil(s.server.SessionStore())
+	s.Equal(1, len(s.server.Errors()))
+	s.EqualError(s.server.Errors()[0], "session provider 'unknown' is not supported")
+
+	s.config.HTTPSessionProvider = "memory"
+	server := NewServer(s.asset, s.config, s.logger, &Support{})
+	server.setupSessionStore(nil)
+	s.Empty(server.Errors())
+
+	sessionStore := server.SessionStore()
+	stores := []SessionStore{}
+	server.Use(SessionManagerWithStore(s.config, sessionStore))
+	server.GET("/session", func(c *Context) {
+		session := c.Session()
+		session.SetKeyPrefix("mysession:")
+		session.Set("username", "dummy")
+		s.Nil(session.Save())
+		s.Contains(session.Key(), "mysession:")
+
+		stores = append(stores, session.(*Session).store)
+	})
+	server.TestHTTPRequest("GET", "/session", nil, nil)
+	server.TestHTTPRequest("GET", "/session", nil, nil)
+	s.Equal(2, len(stores))
+	s.Same(sessionStore, stores[0])
+	s.Same(sessionStore, stores[1])
+
+	// The session's key prefix doesn't change the shared session store.
+	s.Equal("session:", sessionStore.KeyPrefix())
+	s.Equal(SessionStoreStats{ActiveCount: 2}, server.SessionStoreStats())
+
+	s.Nil(server.CloseSessionStore())
+	s.Equal(SessionStoreStats{}, server.SessionStoreStats())
 }
 
 func (s *SessionManagerSuite) TestSessionCookieStore() {
@@ -86,6 +273,95 @@ func (s *SessionManagerSuite) TestSessionCookieStore() {
 	testSessionOps(s, session)
 }
 
+func (s *SessionManagerSuite) TestSessionMemoryStore() {
+	c, _ := NewTestContext(s.recorder)
+	c.Request = &http.Request{}
+	s.config.HTTPSessionProvider = "memory"
+	SessionManager(s.config)(c)
+
+	session := c.Session()
+	session.SetKeyPrefix("mysession:")
+	testSessionOps(s, session)
+	s.Contains(session.Key(), "mysession:")
+}
+
+func (s *SessionManagerSuite) TestSessionMemoryStoreEvictionAndExpiry() {
+	s.config.HTTPSessionProvider = "memory"
+	s.config.HTTPSessionMemoryMaxEntries = 2
+	sessionStore, err := newSessionStore(s.config, nil)
+	s.Nil(err)
+
+	save := func(maxAge int) *http.Request {
+		session, err := sessionStore.New(&http.Request{}, "_session")
+		s.Nil(err)
+		session.Options.MaxAge = maxAge
+		session.Values["id"] = 1
+
+		recorder := httptest.NewRecorder()
+		s.Nil(sessionStore.Save(&http.Request{}, recorder, session))
+		return &http.Request{Header: http.Header{"Cookie": recorder.Header()["Set-Cookie"]}}
+	}
+
+	load := func(req *http.Request) bool {
+		session, err := sessionStore.New(req, "_session")
+		s.Nil(err)
+		if !session.IsNew {
+			s.Equal(1, session.Values["id"])
+		}
+
+		return !session.IsNew
+	}
+
+	// The least recently used session is evicted.
+	first, second := save(3600), save(3600)
+	s.True(load(first))
+	third := save(3600)
+	s.Equal(SessionStoreStats{ActiveCount: 2}, sessionStore.(*sessionstore.MemoryStore).Stats())
+	s.True(load(first))
+	s.False(load(second))
+	s.True(load(third))
+
+	// The expired session is removed when it is read.
+	now := time.Now()
+	sessionStore.(*sessionstore.MemoryStore).SetClock(func() time.Time { return now })
+	expiring := save(1)
+	s.True(load(expiring))
+	now = now.Add(time.Second)
+	s.False(load(expiring))
+	s.Equal(SessionStoreStats{ActiveCount: 1}, sessionStore.(*sessionstore.MemoryStore).Stats())
+
+	// The expired session which is never read again is swept when another session is saved, so that it doesn't evict
+	// the session that is still valid.
+	save(1)
+	s.Equal(SessionStoreStats{ActiveCount: 2}, sessionStore.(*sessionstore.MemoryStore).Stats())
+	now = now.Add(time.Minute)
+	fourth := save(3600)
+	s.Equal(SessionStoreStats{ActiveCount: 2}, sessionStore.(*sessionstore.MemoryStore).Stats())
+	s.True(load(third))
+	s.True(load(fourth))
+
+	s.Nil(sessionStore.(io.Closer).Close())
+	s.False(load(third))
+	s.Equal(SessionStoreStats{}, sessionStore.(*sessionstore.MemoryStore).Stats())
+}
+
+func (s *SessionManagerSuite) TestSessionPostgresStore() {
+	s.config.HTTPSessionProvider = "postgres"
+	_, err := newSessionStore(s.config, nil)
+	s.EqualError(err, "database 'primary' for the postgres session store is not defined")
+
+	// The session store is created before the database is connected.
+	db := NewDB(&DBConfig{}, nil, s.logger, &Support{})
+	sessionStore, err := newSessionStore(s.config, &DBManager{databases: map[string]*DB{"primary": db}})
+	s.Nil(err)
+	defer sessionStore.(io.Closer).Close()
+
+	session, err := sessionStore.New(&http.Request{}, s.config.HTTPSessionName)
+	s.Nil(err)
+	s.EqualError(sessionStore.Save(&http.Request{}, httptest.NewRecorder(), session), "database for the postgres session store is not connected")
+	s.Equal(SessionStoreStats{}, sessionStore.(*sessionstore.PostgresStore).Stats())
+}
+
 func (s *SessionManagerSuite) TestSessionRedisStore() {
 	c, _ := NewTestContext(s.recorder)
 	c.Request = &http.Request{}
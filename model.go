@@ -1,8 +1,238 @@
 package appy
 
-// Model manages the data, logic and rules.
-type Model struct {
-	ID        int `db:"id"`
-	CreatedAt int `db:"created_at"`
-	UpdatedAt int `db:"updated_at"`
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/go-pg/pg/v9/orm"
+)
+
+type (
+	// Model manages the data, logic and rules. It provides the `id` primary key, and the `created_at`/`updated_at`
+	// timestamps which are set automatically on insert/update.
+	//
+	// Note that a model that implements its own go-pg's BeforeInsert/BeforeUpdate hooks needs to call the embedded
+	// Model's hooks to keep the timestamps updated.
+	Model struct {
+		ID        int64     `pg:"id,pk"`
+		CreatedAt time.Time `pg:"created_at"`
+		UpdatedAt time.Time `pg:"updated_at"`
+	}
+
+	// SoftDeleteModel is a Model that sets `deleted_at` instead of deleting the row when it is destroyed. The soft
+	// deleted rows are excluded from the queries by default, use `Deleted()` on the query to include them only and
+	// `ForceDelete()` to delete them permanently.
+	SoftDeleteModel struct {
+		Model
+		DeletedAt time.Time `pg:"deleted_at,soft_delete"`
+	}
+
+	// ModelBeforeValidateHook is called before the model is validated in DB.Save/CreateModel/UpdateModel.
+	ModelBeforeValidateHook interface {
+		BeforeValidate(tx *DBTx) error
+	}
+
+	// ModelValidateHook validates the model in DB.Save/CreateModel/UpdateModel.
+	ModelValidateHook interface {
+		Validate(tx *DBTx) error
+	}
+
+	// ModelAfterValidateHook is called after the model is validated in DB.Save/CreateModel/UpdateModel.
+	ModelAfterValidateHook interface {
+		AfterValidate(tx *DBTx) error
+	}
+
+	// ModelBeforeSaveHook is called before the model is inserted/updated in DB.Save/CreateModel/UpdateModel.
+	ModelBeforeSaveHook interface {
+		BeforeSave(tx *DBTx) error
+	}
+
+	// ModelAfterSaveHook is called after the model is inserted/updated in DB.Save/CreateModel/UpdateModel.
+	ModelAfterSaveHook interface {
+		AfterSave(tx *DBTx) error
+	}
+
+	// ModelBeforeDestroyHook is called before the model is deleted in DB.Destroy.
+	ModelBeforeDestroyHook interface {
+		BeforeDestroy(tx *DBTx) error
+	}
+
+	// ModelAfterDestroyHook is called after the model is deleted in DB.Destroy.
+	ModelAfterDestroyHook interface {
+		AfterDestroy(tx *DBTx) error
+	}
+)
+
+// BeforeInsert is a go-pg's hook that sets the `created_at`/`updated_at` timestamps before the model is inserted.
+func (m *Model) BeforeInsert(c context.Context) (context.Context, error) {
+	now := time.Now()
+	if m.CreatedAt.IsZero() {
+		m.CreatedAt = now
+	}
+
+	m.UpdatedAt = now
+	return c, nil
+}
+
+// BeforeUpdate is a go-pg's hook that sets the `updated_at` timestamp before the model is updated.
+func (m *Model) BeforeUpdate(c context.Context) (context.Context, error) {
+	m.UpdatedAt = time.Now()
+	return c, nil
+}
+
+// CreateModel validates the model with its `binding` struct tags and inserts the model with the before/after validate
+// and save hooks in a transaction. It returns ValidationErrors if the model is invalid.
+func (db *DB) CreateModel(model interface{}) error {
+	if _, err := modelTable(model); err != nil {
+		return err
+	}
+
+	return db.RunInTransaction(func(tx *DBTx) error {
+		return saveModel(tx, model, true)
+	})
+}
+
+// Destroy deletes the model by its primary key with the before/after destroy hooks in a transaction. The
+// SoftDeleteModel is soft deleted.
+func (db *DB) Destroy(model interface{}) error {
+	if _, err := modelTable(model); err != nil {
+		return err
+	}
+
+	return db.RunInTransaction(func(tx *DBTx) error {
+		if hook, ok := model.(ModelBeforeDestroyHook); ok {
+			if err := hook.BeforeDestroy(tx); err != nil {
+				return err
+			}
+		}
+
+		if _, err := tx.Model(model).WherePK().Delete(); err != nil {
+			return err
+		}
+
+		if hook, ok := model.(ModelAfterDestroyHook); ok {
+			return hook.AfterDestroy(tx)
+		}
+
+		return nil
+	})
+}
+
+// Find selects the model by its primary key. It returns `pg.ErrNoRows` if the model is not found or soft deleted.
+func (db *DB) Find(model interface{}, id interface{}) error {
+	table, err := modelTable(model)
+	if err != nil {
+		return err
+	}
+
+	if len(table.PKs) != 1 {
+		return fmt.Errorf("model '%s' must have exactly 1 primary key", table.TypeName)
+	}
+
+	if db.DB == nil {
+		return ErrDBNotConnected
+	}
+
+	return db.Model(model).Where("?TableAlias.? = ?", DBSafeQuery(string(table.PKs[0].Column)), id).Select()
+}
+
+// Save inserts the model if its primary key is zero like CreateModel, or updates the model by its primary key otherwise
+// like UpdateModel.
+func (db *DB) Save(model interface{}) error {
+	table, err := modelTable(model)
+	if err != nil {
+		return err
+	}
+
+	isNew := true
+	strct := reflect.ValueOf(model).Elem()
+	for _, pk := range table.PKs {
+		if !pk.HasZeroValue(strct) {
+			isNew = false
+		}
+	}
+
+	return db.RunInTransaction(func(tx *DBTx) error {
+		return saveModel(tx, model, isNew)
+	})
+}
+
+// UpdateModel validates the model with its `binding` struct tags and updates the model by its primary key with the
+// before/after validate and save hooks in a transaction. It returns ValidationErrors if the model is invalid.
+func (db *DB) UpdateModel(model interface{}) error {
+	if _, err := modelTable(model); err != nil {
+		return err
+	}
+
+	return db.RunInTransaction(func(tx *DBTx) error {
+		return saveModel(tx, model, false)
+	})
+}
+
+// Where returns the query for the model with the condition, e.g. `db.Where(&users, "email = ?", email).Select()`.
+func (db *DB) Where(model interface{}, condition string, params ...interface{}) *DBQuery {
+	return db.Model(model).Where(condition, params...)
+}
+
+func saveModel(tx *DBTx, model interface{}, isNew bool) error {
+	if hook, ok := model.(ModelBeforeValidateHook); ok {
+		if err := hook.BeforeValidate(tx); err != nil {
+			return err
+		}
+	}
+
+	if err := ValidateStruct(model); err != nil {
+		return err
+	}
+
+	if hook, ok := model.(ModelValidateHook); ok {
+		if err := hook.Validate(tx); err != nil {
+			return err
+		}
+	}
+
+	if hook, ok := model.(ModelAfterValidateHook); ok {
+		if err := hook.AfterValidate(tx); err != nil {
+			return err
+		}
+	}
+
+	if hook, ok := model.(ModelBeforeSaveHook); ok {
+		if err := hook.BeforeSave(tx); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	if isNew {
+		_, err = tx.Model(model).Insert()
+	} else {
+		_, err = tx.Model(model).WherePK().Update()
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if hook, ok := model.(ModelAfterSaveHook); ok {
+		return hook.AfterSave(tx)
+	}
+
+	return nil
+}
+
+func modelTable(model interface{}) (*orm.Table, error) {
+	typ := reflect.TypeOf(model)
+	if typ == nil || typ.Kind() != reflect.Ptr || typ.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("model must be a pointer to struct, got %T", model)
+	}
+
+	table := orm.GetTable(typ.Elem())
+	if len(table.PKs) < 1 {
+		return nil, fmt.Errorf("model '%s' must have a primary key", typ.Elem().Name())
+	}
+
+	return table, nil
 }
@@ -0,0 +1,99 @@
+package appy
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type (
+	ModelSuite struct {
+		TestSuite
+	}
+
+	modelUser struct {
+		Model
+		Email string `binding:"omitempty,email"`
+
+		afterSaveError error
+		calls          []string
+		validateError  error
+	}
+
+	modelPost struct {
+		SoftDeleteModel
+		Title string
+	}
+)
+
+func (u *modelUser) BeforeValidate(tx *DBTx) error {
+	u.calls = append(u.calls, "BeforeValidate")
+	return nil
+}
+
+func (u *modelUser) Validate(tx *DBTx) error {
+	u.calls = append(u.calls, "Validate")
+	return u.validateError
+}
+
+func (u *modelUser) AfterValidate(tx *DBTx) error {
+	u.calls = append(u.calls, "AfterValidate")
+	return nil
+}
+
+func (u *modelUser) BeforeSave(tx *DBTx) error {
+	u.calls = append(u.calls, "BeforeSave")
+	return nil
+}
+
+func (u *modelUser) AfterSave(tx *DBTx) error {
+	u.calls = append(u.calls, "AfterSave")
+	return u.afterSaveError
+}
+
+func (u *modelUser) BeforeDestroy(tx *DBTx) error {
+	u.calls = append(u.calls, "BeforeDestroy")
+	return nil
+}
+
+func (u *modelUser) AfterDestroy(tx *DBTx) error {
+	u.calls = append(u.calls, "AfterDestroy")
+	return nil
+}
+
+func (s *ModelSuite) TestTimestamps() {
+	user := &modelUser{}
+	_, err := user.BeforeInsert(context.Background())
+	s.Nil(err)
+	s.False(user.CreatedAt.IsZero())
+	s.Equal(user.CreatedAt, user.UpdatedAt)
+
+	createdAt := user.CreatedAt
+	time.Sleep(time.Millisecond)
+	_, err = user.BeforeUpdate(context.Background())
+	s.Nil(err)
+	s.Equal(createdAt, user.CreatedAt)
+	s.True(user.UpdatedAt.After(createdAt))
+}
+
+func (s *ModelSuite) TestNotConnected() {
+	db := NewDB(&DBConfig{}, nil, nil, &Support{})
+	s.Equal(ErrDBNotConnected, db.Save(&modelUser{}))
+	s.Equal(ErrDBNotConnected, db.CreateModel(&modelUser{}))
+	s.Equal(ErrDBNotConnected, db.UpdateModel(&modelUser{}))
+	s.Equal(ErrDBNotConnected, db.Destroy(&modelUser{}))
+	s.Equal(ErrDBNotConnected, db.Find(&modelUser{}, 1))
+}
+
+func (s *ModelSuite) TestInvalidModel() {
+	db := NewDB(&DBConfig{}, nil, nil, &Support{})
+	s.EqualError(db.Find(modelUser{}, 1), "model must be a pointer to struct, got appy.modelUser")
+	s.EqualError(db.Save(nil), "model must be a pointer to struct, got <nil>")
+	s.EqualError(db.CreateModel(nil), "model must be a pointer to struct, got <nil>")
+	s.EqualError(db.UpdateModel(nil), "model must be a pointer to struct, got <nil>")
+	s.EqualError(db.Destroy(nil), "model must be a pointer to struct, got <nil>")
+}
+
+func TestModelSuite(t *testing.T) {
+	RunTestSuite(t, new(ModelSuite))
+}
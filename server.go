@@ -29,11 +29,13 @@ type (
 	Server struct {
 		asset        *Asset
 		config       *Config
+		errors       []error
 		http         *http.Server
 		https        *http.Server
 		logger       *Logger
 		middleware   []HandlerFunc
 		router       *Router
+		sessionStore SessionStore
 		spaResources []*spaResource
 		support      Supporter
 	}
@@ -51,6 +53,9 @@ func init() {
 
 // NewServer initializes Server instance.
 func NewServer(asset *Asset, config *Config, logger *Logger, support Supporter) *Server {
+	// Configure the validator before any request binding so that its validation errors use the `json` field names.
+	validatorEngine()
+
 	router := newRouter()
 
 	httpServer := &http.Server{
@@ -102,6 +107,47 @@ func (s *Server) HTTPS() *http.Server {
 	return s.https
 }
 
+// Errors returns the server errors, e.g. the session store fails to be created.
+func (s *Server) Errors() []error {
+	return s.errors
+}
+
+// SessionStore returns the session store with HTTP_SESSION_PROVIDER which is created once when the app is initialized
+// and shared across the requests, or nil if it fails to be created which is reported by Errors.
+func (s *Server) SessionStore() SessionStore {
+	return s.sessionStore
+}
+
+// SessionStoreStats returns the session store's connection pool statistics if the session store implements
+// `Stats() SessionStoreStats`.
+func (s *Server) SessionStoreStats() SessionStoreStats {
+	if sessionStore, ok := s.sessionStore.(interface{ Stats() SessionStoreStats }); ok {
+		return sessionStore.Stats()
+	}
+
+	return SessionStoreStats{}
+}
+
+// CloseSessionStore closes the session store if it implements `Close() error`, which should be called after the
+// in-flight HTTP requests are drained.
+func (s *Server) CloseSessionStore() error {
+	if sessionStore, ok := s.sessionStore.(io.Closer); ok {
+		return sessionStore.Close()
+	}
+
+	return nil
+}
+
+func (s *Server) setupSessionStore(dbManager *DBManager) {
+	sessionStore, err := newSessionStore(s.config, dbManager)
+	if err != nil {
+		s.errors = append(s.errors, err)
+		return
+	}
+
+	s.sessionStore = sessionStore
+}
+
 // HTMLRenderer returns the HTML renderer instance.
 func (s *Server) HTMLRenderer() multitemplate.Renderer {
 	return s.router.HTMLRender.(multitemplate.Renderer)
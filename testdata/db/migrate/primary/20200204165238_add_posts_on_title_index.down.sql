@@ -0,0 +1,2 @@
+-- appy:no-transaction
+DROP INDEX CONCURRENTLY posts_on_title;
@@ -0,0 +1,2 @@
+-- appy:no-transaction
+CREATE INDEX CONCURRENTLY posts_on_title ON posts (title);
@@ -0,0 +1 @@
+CREATE TABLE tags (id SERIAL PRIMARY KEY);
@@ -1,15 +1,112 @@
 package appy
 
 import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+
 	"github.com/stretchr/testify/suite"
 )
 
 type (
 	// TestSuite is a basic testing suite with methods for storing and retrieving the current *testing.T context.
-	TestSuite = suite.Suite
+	//
+	// With SetupDB called in SetupSuite, each test runs in a database transaction which is rolled back in
+	// TearDownTest. Note that the suite which implements its own SetupTest/TearDownTest/TearDownSuite needs to call the
+	// TestSuite's ones.
+	TestSuite struct {
+		suite.Suite
+		dbManager *DBManager
+	}
+
+	dbTestTx struct {
+		counter  int
+		enabled  bool
+		mu       *sync.Mutex
+		poolSize int
+	}
 )
 
 var (
 	// RunTestSuite takes a testing suite and runs all of the tests attached to it.
 	RunTestSuite = suite.Run
 )
+
+// SetupDB connects all the databases in the DB manager for APPY_ENV=test and runs the pending migrations once per
+// database in the test process. Each database is connected with a single connection, so that the DB calls from the
+// handlers under Server.TestHTTPRequest see the test data, and the transactions started by DB.Begin or
+// DB.RunInTransaction run as the savepoints in the test transaction. Note that the transactions started by the
+// underlying go-pg handle, i.e. DB.DB, are not isolated.
+func (s *TestSuite) SetupDB(dbManager *DBManager) {
+	s.Require().Equal("test", os.Getenv("APPY_ENV"), "the database test isolation only works with APPY_ENV=test")
+	s.Require().Empty(dbManager.Errors())
+	s.Require().NoError(dbManager.connectTest(context.Background()))
+
+	s.dbManager = dbManager
+}
+
+// SetupTest begins the test transaction for all the databases if SetupDB is called.
+func (s *TestSuite) SetupTest() {
+	if s.dbManager == nil {
+		return
+	}
+
+	s.Require().NoError(s.dbManager.beginTest())
+}
+
+// TearDownTest rolls back the test transaction for all the databases if SetupDB is called.
+func (s *TestSuite) TearDownTest() {
+	if s.dbManager == nil {
+		return
+	}
+
+	s.Require().NoError(s.dbManager.rollbackTest())
+}
+
+// TearDownSuite closes all the databases if SetupDB is called.
+func (s *TestSuite) TearDownSuite() {
+	if s.dbManager == nil {
+		return
+	}
+
+	s.Require().NoError(s.dbManager.closeTest(context.Background()))
+	s.dbManager = nil
+}
+
+func newDBTestTx(poolSize int) *dbTestTx {
+	return &dbTestTx{mu: &sync.Mutex{}, poolSize: poolSize}
+}
+
+func (tx *dbTestTx) begin() {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	tx.counter = 0
+	tx.enabled = true
+}
+
+func (tx *dbTestTx) end() {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	tx.counter = 0
+	tx.enabled = false
+}
+
+// savepoint returns a new savepoint name if the test transaction is in progress.
+func (tx *dbTestTx) savepoint() (string, bool) {
+	if tx == nil {
+		return "", false
+	}
+
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	if !tx.enabled {
+		return "", false
+	}
+
+	tx.counter++
+	return fmt.Sprintf("appy_test_%d", tx.counter), true
+}
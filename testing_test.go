@@ -0,0 +1,40 @@
+package appy
+
+import (
+	"testing"
+)
+
+type TestingSuite struct {
+	TestSuite
+}
+
+func (s *TestingSuite) TestDBTestTxSavepoint() {
+	var nilTx *dbTestTx
+	_, ok := nilTx.savepoint()
+	s.False(ok)
+
+	tx := newDBTestTx(10)
+	s.Equal(10, tx.poolSize)
+
+	_, ok = tx.savepoint()
+	s.False(ok)
+
+	tx.begin()
+	for _, expected := range []string{"appy_test_1", "appy_test_2", "appy_test_3"} {
+		savepoint, ok := tx.savepoint()
+		s.True(ok)
+		s.Equal(expected, savepoint)
+	}
+
+	tx.begin()
+	savepoint, _ := tx.savepoint()
+	s.Equal("appy_test_1", savepoint)
+
+	tx.end()
+	_, ok = tx.savepoint()
+	s.False(ok)
+}
+
+func TestTestingSuite(t *testing.T) {
+	RunTestSuite(t, new(TestingSuite))
+}
@@ -0,0 +1,108 @@
+package appy
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+type (
+	// ValidationError is a field validation error with the failed validation tag and its param, e.g. `min` and `8`
+	// for `binding:"min=8"`. The field is named after its `json` struct tag if there is one.
+	ValidationError struct {
+		Field string
+		Tag   string
+		Param string
+	}
+
+	// ValidationErrors is a list of field validation errors which is returned by ValidateStruct, DB.Save and the
+	// request binding.
+	ValidationErrors []ValidationError
+
+	// ValidationFieldLevel contains the field information for the custom validation func.
+	ValidationFieldLevel = validator.FieldLevel
+
+	// ValidationFunc is the custom validation func that is registered with RegisterValidation.
+	ValidationFunc = validator.Func
+)
+
+var validatorEngineOnce sync.Once
+
+func (e ValidationErrors) Error() string {
+	msgs := []string{}
+	for _, fe := range e {
+		msgs = append(msgs, fmt.Sprintf("'%s' failed on the '%s' validation", fe.Field, fe.Tag))
+	}
+
+	return strings.Join(msgs, ", ")
+}
+
+// Translate returns the field error map with the messages translated by `validations.<tag>` key in the locale, with
+// the `Field` and `Param` template data.
+func (e ValidationErrors) Translate(i18n *I18n, locale string) map[string][]string {
+	errs := map[string][]string{}
+	for _, fe := range e {
+		msg := ""
+		if i18n != nil {
+			msg = i18n.T("validations."+fe.Tag, H{"Field": fe.Field, "Param": fe.Param}, locale)
+		}
+
+		if msg == "" {
+			msg = fmt.Sprintf("failed on the '%s' validation", fe.Tag)
+		}
+
+		errs[fe.Field] = append(errs[fe.Field], msg)
+	}
+
+	return errs
+}
+
+// RegisterValidation registers the custom validation func with the tag which can then be used by the `binding` struct
+// tag for both the models and the request binding structs.
+func RegisterValidation(tag string, fn ValidationFunc) error {
+	return validatorEngine().RegisterValidation(tag, fn)
+}
+
+// ValidateStruct validates the struct with the `binding` struct tags. It returns ValidationErrors if any field is
+// invalid.
+func ValidateStruct(obj interface{}) error {
+	return newValidationErrors(validatorEngine().Struct(obj))
+}
+
+func newValidationErrors(err error) error {
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return err
+	}
+
+	errs := ValidationErrors{}
+	for _, fe := range verrs {
+		errs = append(errs, ValidationError{Field: fe.Field(), Tag: fe.Tag(), Param: fe.Param()})
+	}
+
+	return errs
+}
+
+// validatorEngine returns the gin's validator engine which is shared with the request binding. It is configured on the
+// first call, instead of on import, to name the fields after their `json` struct tags. Note that this also applies to
+// the other gin routers in the same process.
+func validatorEngine() *validator.Validate {
+	engine := binding.Validator.Engine().(*validator.Validate)
+	validatorEngineOnce.Do(func() {
+		engine.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+			if name == "-" {
+				return ""
+			}
+
+			return name
+		})
+	})
+
+	return engine
+}
@@ -0,0 +1,39 @@
+package appy
+
+import (
+	"testing"
+)
+
+type ValidatorSuite struct {
+	TestSuite
+}
+
+func (s *ValidatorSuite) TestValidateStruct() {
+	type user struct {
+		Model
+		Email    string `json:"email" binding:"required,email"`
+		Username string `binding:"required,appyUsername"`
+	}
+
+	s.Nil(RegisterValidation("appyUsername", func(fl ValidationFieldLevel) bool {
+		return fl.Field().String() != "admin"
+	}))
+
+	err := ValidateStruct(&user{Email: "john", Username: "admin"})
+	s.Equal(ValidationErrors{
+		{Field: "email", Tag: "email"},
+		{Field: "Username", Tag: "appyUsername"},
+	}, err)
+	s.EqualError(err, "'email' failed on the 'email' validation, 'Username' failed on the 'appyUsername' validation")
+	s.Equal(map[string][]string{
+		"email":    {"failed on the 'email' validation"},
+		"Username": {"failed on the 'appyUsername' validation"},
+	}, err.(ValidationErrors).Translate(nil, "en"))
+
+	s.Nil(ValidateStruct(&user{Email: "john@appist.io", Username: "john"}))
+	s.NotNil(ValidateStruct("john"))
+}
+
+func TestValidatorSuite(t *testing.T) {
+	RunTestSuite(t, new(ValidatorSuite))
+}
@@ -0,0 +1,372 @@
+package appy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/appist/appy/internal/jobqueue"
+	uuid "github.com/satori/go.uuid"
+)
+
+type (
+	// Job is the unit of work that is enqueued and processed by the worker in the background.
+	Job = jobqueue.Job
+
+	// JobHandler processes a job. Returning an error retries the job with backoff until it runs out of retries which
+	// then moves it into the dead set.
+	JobHandler func(ctx context.Context, job *Job) error
+
+	// JobOption configures how a job should be enqueued.
+	JobOption func(*jobOptions)
+
+	// Worker processes the jobs outside of the HTTP request cycle using the redis backend, or the memory backend when
+	// APPY_ENV=test. With the redis backend, a job that isn't done within WORKER_PROCESSING_TIMEOUT, e.g. the worker
+	// died mid-job, is pushed back into its queue to be processed again.
+	Worker struct {
+		cancel   context.CancelFunc
+		config   *Config
+		ctx      context.Context
+		handlers map[string]JobHandler
+		logger   *Logger
+		mu       *sync.RWMutex
+		queue    jobqueue.Queue
+		quit     chan struct{}
+		wg       *sync.WaitGroup
+	}
+
+	jobOptions struct {
+		at       time.Time
+		maxRetry int
+		queue    string
+	}
+)
+
+const (
+	// DefaultJobQueue is the queue that a job is enqueued into if JobQueue option isn't specified.
+	DefaultJobQueue = "default"
+)
+
+// JobQueue specifies the queue that the job should be enqueued into.
+func JobQueue(name string) JobOption {
+	return func(opts *jobOptions) {
+		opts.queue = name
+	}
+}
+
+// JobMaxRetry specifies how many times the job should be retried before it is moved into the dead set.
+func JobMaxRetry(maxRetry int) JobOption {
+	return func(opts *jobOptions) {
+		opts.maxRetry = maxRetry
+	}
+}
+
+// JobProcessAt schedules the job to be processed at the specified time.
+func JobProcessAt(at time.Time) JobOption {
+	return func(opts *jobOptions) {
+		opts.at = at
+	}
+}
+
+// JobProcessIn schedules the job to be processed after the specified duration.
+func JobProcessIn(d time.Duration) JobOption {
+	return func(opts *jobOptions) {
+		opts.at = time.Now().Add(d)
+	}
+}
+
+// NewWorker initializes Worker instance.
+func NewWorker(config *Config, logger *Logger) *Worker {
+	var queue jobqueue.Queue
+
+	if config.AppyEnv == "test" {
+		queue = jobqueue.NewMemoryQueue()
+	} else {
+		queue = jobqueue.NewRedisQueueWithPool(
+			NewRedisPool(RedisPoolConfig{
+				Addr:            config.WorkerRedisAddr,
+				Auth:            config.WorkerRedisAuth,
+				Db:              config.WorkerRedisDb,
+				IdleTimeout:     config.WorkerRedisIdleTimeout,
+				MaxConnLifetime: config.WorkerRedisMaxConnLifetime,
+				MaxActive:       config.WorkerRedisMaxActive,
+				MaxIdle:         config.WorkerRedisMaxIdle,
+				Wait:            config.WorkerRedisWait,
+			}),
+			config.WorkerProcessingTimeout,
+		)
+	}
+
+	return &Worker{
+		config:   config,
+		handlers: map[string]JobHandler{},
+		logger:   logger,
+		mu:       &sync.RWMutex{},
+		queue:    queue,
+		wg:       &sync.WaitGroup{},
+	}
+}
+
+// Config returns the worker's configuration.
+func (w *Worker) Config() *Config {
+	return w.config
+}
+
+// Register registers the handler that processes the jobs with the specified name.
+func (w *Worker) Register(name string, handler JobHandler) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.handlers[name] = handler
+}
+
+// Enqueue marshals the payload into JSON and pushes the job into the queue to be processed by the handler that is
+// registered with the specified name.
+func (w *Worker) Enqueue(name string, payload interface{}, opts ...JobOption) (*Job, error) {
+	options := &jobOptions{
+		maxRetry: w.config.WorkerMaxRetry,
+		queue:    DefaultJobQueue,
+	}
+
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	job := &Job{
+		ID:         uuid.NewV4().String(),
+		Name:       name,
+		Queue:      options.queue,
+		Payload:    data,
+		MaxRetry:   options.maxRetry,
+		EnqueuedAt: time.Now(),
+	}
+
+	if !options.at.IsZero() && options.at.After(job.EnqueuedAt) {
+		return job, w.queue.Schedule(job, options.at)
+	}
+
+	return job, w.queue.Enqueue(job)
+}
+
+// Pending returns the jobs that are waiting to be processed in the queue.
+func (w *Worker) Pending(queue string) ([]*Job, error) {
+	return w.queue.Pending(queue)
+}
+
+// Scheduled returns the jobs that are scheduled to be processed later, including those waiting to be retried.
+func (w *Worker) Scheduled() ([]*Job, error) {
+	return w.queue.Scheduled()
+}
+
+// Dead returns the jobs that have run out of retries.
+func (w *Worker) Dead() ([]*Job, error) {
+	return w.queue.Dead()
+}
+
+// Drain processes all the pending jobs in WORKER_QUEUES synchronously until they are empty, which is useful for unit
+// test with APPY_ENV=test. Note that the failed jobs are scheduled for retry and won't be drained.
+func (w *Worker) Drain(ctx context.Context) error {
+	for {
+		job, err := w.queue.Dequeue(w.queues(), 0)
+		if err != nil {
+			return err
+		}
+
+		if job == nil {
+			return nil
+		}
+
+		w.process(ctx, job)
+	}
+}
+
+// Info returns the worker info.
+func (w *Worker) Info() string {
+	return fmt.Sprintf("* Worker queues: %s (concurrency: %d)", strings.Join(w.queues(), ", "), w.concurrency())
+}
+
+// Start starts processing the jobs in the background with WORKER_CONCURRENCY goroutines.
+func (w *Worker) Start() {
+	w.ctx, w.cancel = context.WithCancel(context.Background())
+	w.quit = make(chan struct{})
+
+	for i := 0; i < w.concurrency(); i++ {
+		w.wg.Add(1)
+		go w.fetch()
+	}
+
+	w.wg.Add(1)
+	go w.poll()
+}
+
+// Shutdown stops fetching new jobs and waits for the in-flight jobs to finish. If the context expires before they
+// finish, the context passed to the job handlers is cancelled and the context's error is returned.
+func (w *Worker) Shutdown(ctx context.Context) error {
+	if w.quit == nil {
+		return nil
+	}
+
+	select {
+	case <-w.quit:
+	default:
+		close(w.quit)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		w.cancel()
+		return nil
+	case <-ctx.Done():
+		w.cancel()
+		return ctx.Err()
+	}
+}
+
+// Close closes the underlying queue connections.
+func (w *Worker) Close() error {
+	return w.queue.Close()
+}
+
+func (w *Worker) concurrency() int {
+	if w.config.WorkerConcurrency < 1 {
+		return 1
+	}
+
+	return w.config.WorkerConcurrency
+}
+
+func (w *Worker) queues() []string {
+	if len(w.config.WorkerQueues) < 1 {
+		return []string{DefaultJobQueue}
+	}
+
+	return w.config.WorkerQueues
+}
+
+func (w *Worker) fetch() {
+	defer w.wg.Done()
+
+	for {
+		select {
+		case <-w.quit:
+			return
+		default:
+		}
+
+		job, err := w.queue.Dequeue(w.queues(), w.config.WorkerPollInterval)
+		if err != nil {
+			w.logger.Error(err)
+			w.wait(w.config.WorkerPollInterval)
+			continue
+		}
+
+		if job != nil {
+			w.process(w.ctx, job)
+		}
+	}
+}
+
+func (w *Worker) poll() {
+	defer w.wg.Done()
+
+	for {
+		if err := w.queue.Promote(time.Now()); err != nil {
+			w.logger.Error(err)
+		}
+
+		if !w.wait(w.config.WorkerPollInterval) {
+			return
+		}
+	}
+}
+
+// wait blocks for the duration and returns false if the worker is shutting down.
+func (w *Worker) wait(d time.Duration) bool {
+	select {
+	case <-w.quit:
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+func (w *Worker) process(ctx context.Context, job *Job) {
+	defer func() {
+		if err := w.queue.Ack(job); err != nil {
+			w.logger.Error(err)
+		}
+	}()
+
+	start := time.Now()
+	err := w.perform(ctx, job)
+	if err == nil {
+		w.logger.Infof("[JOB] %s %s (queue: %s) DONE in %s", job.ID, job.Name, job.Queue, time.Since(start))
+		return
+	}
+
+	job.Error = err.Error()
+	job.FailedAt = time.Now()
+
+	if job.Retry < job.MaxRetry {
+		backoff := jobRetryBackoff(job.Retry)
+		job.Retry++
+
+		w.logger.Warnf("[JOB] %s %s (queue: %s) FAILED in %s, retrying in %s (%d/%d): %s", job.ID, job.Name, job.Queue,
+			time.Since(start), backoff, job.Retry, job.MaxRetry, job.Error)
+
+		if err := w.queue.Schedule(job, job.FailedAt.Add(backoff)); err != nil {
+			w.logger.Error(err)
+		}
+
+		return
+	}
+
+	w.logger.Errorf("[JOB] %s %s (queue: %s) FAILED in %s, moved to the dead set after %d retries: %s", job.ID,
+		job.Name, job.Queue, time.Since(start), job.Retry, job.Error)
+
+	if err := w.queue.Kill(job); err != nil {
+		w.logger.Error(err)
+	}
+}
+
+func (w *Worker) perform(ctx context.Context, job *Job) (err error) {
+	w.mu.RLock()
+	handler, ok := w.handlers[job.Name]
+	w.mu.RUnlock()
+
+	if !ok {
+		return fmt.Errorf("job handler for '%s' is not registered", job.Name)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job handler for '%s' panicked: %v", job.Name, r)
+		}
+	}()
+
+	return handler(ctx, job)
+}
+
+// jobRetryBackoff returns the exponential backoff with jitter for the retry count which is similar to Sidekiq's,
+// i.e. 15s, 16s, 31s, 96s, 271s, ... ~20 days for the 25th retry.
+func jobRetryBackoff(retry int) time.Duration {
+	seconds := math.Pow(float64(retry), 4) + 15 + float64(rand.Intn(30)*(retry+1))
+
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,284 @@
+package appy_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/appist/appy"
+	"github.com/gomodule/redigo/redis"
+)
+
+type WorkerSuite struct {
+	appy.TestSuite
+	asset   *appy.Asset
+	config  *appy.Config
+	logger  *appy.Logger
+	support appy.Supporter
+	worker  *appy.Worker
+}
+
+func (s *WorkerSuite) SetupTest() {
+	os.Setenv("APPY_ENV", "test")
+	os.Setenv("APPY_MASTER_KEY", "481e5d98a31585148b8b1dfb6a3c0465")
+	os.Setenv("HTTP_CSRF_SECRET", "481e5d98a31585148b8b1dfb6a3c0465")
+	os.Setenv("HTTP_SESSION_SECRETS", "481e5d98a31585148b8b1dfb6a3c0465")
+	os.Setenv("WORKER_QUEUES", "critical,default")
+
+	s.support = &appy.Support{}
+	s.logger, _, _ = appy.NewFakeLogger()
+	s.asset = appy.NewAsset(http.Dir("testdata/app"), map[string]string{
+		"docker": "testdata/app/.docker",
+		"config": "testdata/app/configs",
+		"locale": "testdata/app/pkg/locales",
+		"view":   "testdata/app/pkg/views",
+		"web":    "testdata/app/web",
+	}, "")
+	s.config = appy.NewConfig(s.asset, s.logger, s.support)
+	s.worker = appy.NewWorker(s.config, s.logger)
+}
+
+func (s *WorkerSuite) TearDownTest() {
+	os.Unsetenv("APPY_ENV")
+	os.Unsetenv("APPY_MASTER_KEY")
+	os.Unsetenv("HTTP_CSRF_SECRET")
+	os.Unsetenv("HTTP_SESSION_SECRETS")
+	os.Unsetenv("WORKER_QUEUES")
+	s.worker.Close()
+}
+
+func (s *WorkerSuite) TestEnqueue() {
+	job, err := s.worker.Enqueue("user.welcome", appy.H{"id": 1})
+	s.Nil(err)
+	s.Equal("user.welcome", job.Name)
+	s.Equal(appy.DefaultJobQueue, job.Queue)
+	s.Equal(25, job.MaxRetry)
+
+	_, err = s.worker.Enqueue("user.welcome", appy.H{"id": 2}, appy.JobQueue("critical"), appy.JobMaxRetry(3))
+	s.Nil(err)
+
+	_, err = s.worker.Enqueue("user.welcome", appy.H{"id": 3}, appy.JobProcessIn(time.Hour))
+	s.Nil(err)
+
+	_, err = s.worker.Enqueue("user.welcome", make(chan int))
+	s.NotNil(err)
+
+	pending, err := s.worker.Pending(appy.DefaultJobQueue)
+	s.Nil(err)
+	s.Equal(1, len(pending))
+
+	pending, err = s.worker.Pending("critical")
+	s.Nil(err)
+	s.Equal(1, len(pending))
+	s.Equal(3, pending[0].MaxRetry)
+
+	scheduled, err := s.worker.Scheduled()
+	s.Nil(err)
+	s.Equal(1, len(scheduled))
+}
+
+func (s *WorkerSuite) TestDrain() {
+	processed := []int{}
+	s.worker.Register("user.welcome", func(ctx context.Context, job *appy.Job) error {
+		var payload struct{ ID int }
+		if err := job.Decode(&payload); err != nil {
+			return err
+		}
+
+		processed = append(processed, payload.ID)
+		return nil
+	})
+
+	s.worker.Enqueue("user.welcome", appy.H{"id": 1})
+	s.worker.Enqueue("user.welcome", appy.H{"id": 2}, appy.JobQueue("critical"))
+	s.Nil(s.worker.Drain(context.Background()))
+	s.Equal([]int{2, 1}, processed)
+
+	pending, err := s.worker.Pending(appy.DefaultJobQueue)
+	s.Nil(err)
+	s.Equal(0, len(pending))
+}
+
+func (s *WorkerSuite) TestRetryAndDead() {
+	s.worker.Register("user.fail", func(ctx context.Context, job *appy.Job) error {
+		return errors.New("boom")
+	})
+	s.worker.Register("user.panic", func(ctx context.Context, job *appy.Job) error {
+		panic("boom")
+	})
+
+	s.worker.Enqueue("user.fail", nil, appy.JobMaxRetry(1))
+	s.worker.Enqueue("user.panic", nil, appy.JobMaxRetry(0))
+	s.worker.Enqueue("user.missing", nil, appy.JobMaxRetry(0))
+	s.Nil(s.worker.Drain(context.Background()))
+
+	scheduled, err := s.worker.Scheduled()
+	s.Nil(err)
+	s.Equal(1, len(scheduled))
+	s.Equal("user.fail", scheduled[0].Name)
+	s.Equal(1, scheduled[0].Retry)
+	s.Equal("boom", scheduled[0].Error)
+
+	dead, err := s.worker.Dead()
+	s.Nil(err)
+	s.Equal(2, len(dead))
+	s.Equal("job handler for 'user.panic' panicked: boom", dead[0].Error)
+	s.Equal("job handler for 'user.missing' is not registered", dead[1].Error)
+}
+
+func (s *WorkerSuite) TestStartAndShutdown() {
+	done := make(chan string, 1)
+	s.worker.Register("user.welcome", func(ctx context.Context, job *appy.Job) error {
+		done <- job.ID
+		return nil
+	})
+
+	s.worker.Start()
+	job, err := s.worker.Enqueue("user.welcome", nil)
+	s.Nil(err)
+
+	select {
+	case id := <-done:
+		s.Equal(job.ID, id)
+	case <-time.After(5 * time.Second):
+		s.Fail("job is not processed in time")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	s.Nil(s.worker.Shutdown(ctx))
+	s.Nil(s.worker.Shutdown(ctx))
+}
+
+func (s *WorkerSuite) TestRedisQueue() {
+	s.config.AppyEnv = "development"
+	s.config.WorkerPollInterval = 100 * time.Millisecond
+	worker := appy.NewWorker(s.config, s.logger)
+	defer worker.Close()
+
+	pool := appy.NewRedisPool(appy.RedisPoolConfig{Addr: s.config.WorkerRedisAddr, Db: s.config.WorkerRedisDb})
+	defer pool.Close()
+
+	conn := pool.Get()
+	defer conn.Close()
+
+	if _, err := conn.Do("PING"); err != nil {
+		s.Fail("redis is unreachable", err.Error())
+		return
+	}
+
+	cleanup := func() {
+		keys, err := redis.Values(conn.Do("KEYS", "jobs:*"))
+		s.Nil(err)
+
+		if len(keys) > 0 {
+			_, err = conn.Do("DEL", keys...)
+			s.Nil(err)
+		}
+	}
+	cleanup()
+	defer cleanup()
+
+	processed := []int{}
+	worker.Register("user.welcome", func(ctx context.Context, job *appy.Job) error {
+		var payload struct{ ID int }
+		if err := job.Decode(&payload); err != nil {
+			return err
+		}
+
+		processing, err := redis.Int(conn.Do("LLEN", "jobs:processing:"+job.Queue))
+		s.Nil(err)
+		s.Equal(1, processing)
+
+		processed = append(processed, payload.ID)
+		return nil
+	})
+	worker.Register("user.fail", func(ctx context.Context, job *appy.Job) error {
+		return errors.New("boom")
+	})
+
+	_, err := worker.Enqueue("user.welcome", appy.H{"id": 1})
+	s.Nil(err)
+
+	_, err = worker.Enqueue("user.welcome", appy.H{"id": 2}, appy.JobQueue("critical"))
+	s.Nil(err)
+
+	_, err = worker.Enqueue("user.welcome", appy.H{"id": 3}, appy.JobProcessIn(time.Hour))
+	s.Nil(err)
+
+	_, err = worker.Enqueue("user.fail", nil, appy.JobMaxRetry(0))
+	s.Nil(err)
+
+	pending, err := worker.Pending(appy.DefaultJobQueue)
+	s.Nil(err)
+	if s.Equal(2, len(pending)) {
+		s.Equal("user.welcome", pending[0].Name)
+	}
+
+	s.Nil(worker.Drain(context.Background()))
+	s.Equal([]int{2, 1}, processed)
+
+	for _, key := range []string{"jobs:queue:default", "jobs:processing:default", "jobs:processing:critical"} {
+		count, err := redis.Int(conn.Do("LLEN", key))
+		s.Nil(err)
+		s.Equal(0, count)
+	}
+
+	leases, err := redis.Int(conn.Do("ZCARD", "jobs:leases"))
+	s.Nil(err)
+	s.Equal(0, leases)
+
+	scheduled, err := worker.Scheduled()
+	s.Nil(err)
+	s.Equal(1, len(scheduled))
+
+	dead, err := worker.Dead()
+	s.Nil(err)
+	if s.Equal(1, len(dead)) {
+		s.Equal("boom", dead[0].Error)
+	}
+
+	// Simulate a worker that died mid-job with its lease expired.
+	done := make(chan string, 1)
+	worker.Register("user.recover", func(ctx context.Context, job *appy.Job) error {
+		done <- job.ID
+		return nil
+	})
+
+	job, err := worker.Enqueue("user.recover", nil)
+	s.Nil(err)
+
+	member, err := redis.Bytes(conn.Do("RPOPLPUSH", "jobs:queue:default", "jobs:processing:default"))
+	s.Nil(err)
+
+	_, err = conn.Do("ZADD", "jobs:leases", time.Now().Add(-time.Minute).Unix(), member)
+	s.Nil(err)
+
+	worker.Start()
+
+	select {
+	case id := <-done:
+		s.Equal(job.ID, id)
+	case <-time.After(5 * time.Second):
+		s.Fail("job is not pushed back into its queue in time")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	s.Nil(worker.Shutdown(ctx))
+
+	processing, err := redis.Int(conn.Do("LLEN", "jobs:processing:default"))
+	s.Nil(err)
+	s.Equal(0, processing)
+}
+
+func (s *WorkerSuite) TestInfo() {
+	s.Equal("* Worker queues: critical, default (concurrency: 25)", s.worker.Info())
+}
+
+func TestWorkerSuite(t *testing.T) {
+	appy.RunTestSuite(t, new(WorkerSuite))
+}